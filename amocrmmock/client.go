@@ -0,0 +1,138 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrmmock
+
+import (
+	"context"
+	"net/url"
+	"sync"
+
+	"github.com/alexeykhan/amocrm"
+)
+
+// Client is a mock of amocrm.Client. Repository methods return the
+// corresponding Mock fields, which are created empty when not set.
+type Client struct {
+	Recorder
+
+	AuthorizeURLFunc func(state, mode string) (*url.URL, error)
+	TokenByCodeFunc  func(ctx context.Context, code string) (amocrm.Token, error)
+	SetTokenFunc     func(token amocrm.Token) error
+	SetDomainFunc    func(domain string) error
+	DoFunc           func(ctx context.Context, method, path string, query url.Values, body, out interface{}) error
+
+	AccountsMock *Accounts
+	LeadsMock    *Leads
+	ContactsMock *Contacts
+
+	mu sync.Mutex
+}
+
+// Verify interface compliance.
+var _ amocrm.Client = (*Client)(nil)
+
+// AuthorizeURL implements amocrm.Client interface.
+func (c *Client) AuthorizeURL(state, mode string) (*url.URL, error) {
+	c.record(context.Background(), "AuthorizeURL", state, mode)
+	if c.AuthorizeURLFunc == nil {
+		return &url.URL{}, nil
+	}
+	return c.AuthorizeURLFunc(state, mode)
+}
+
+// TokenByCode implements amocrm.Client interface.
+func (c *Client) TokenByCode(code string) (amocrm.Token, error) {
+	return c.TokenByCodeContext(context.Background(), code)
+}
+
+// TokenByCodeContext implements amocrm.Client interface.
+func (c *Client) TokenByCodeContext(ctx context.Context, code string) (amocrm.Token, error) {
+	c.record(ctx, "TokenByCode", code)
+	if c.TokenByCodeFunc == nil {
+		return nil, nil
+	}
+	return c.TokenByCodeFunc(ctx, code)
+}
+
+// SetToken implements amocrm.Client interface.
+func (c *Client) SetToken(token amocrm.Token) error {
+	c.record(context.Background(), "SetToken", token)
+	if c.SetTokenFunc == nil {
+		return nil
+	}
+	return c.SetTokenFunc(token)
+}
+
+// SetDomain implements amocrm.Client interface.
+func (c *Client) SetDomain(domain string) error {
+	c.record(context.Background(), "SetDomain", domain)
+	if c.SetDomainFunc == nil {
+		return nil
+	}
+	return c.SetDomainFunc(domain)
+}
+
+// Accounts implements amocrm.Client interface.
+func (c *Client) Accounts() amocrm.Accounts {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.AccountsMock == nil {
+		c.AccountsMock = &Accounts{}
+	}
+	return c.AccountsMock
+}
+
+// Leads implements amocrm.Client interface.
+func (c *Client) Leads() amocrm.Leads {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.LeadsMock == nil {
+		c.LeadsMock = &Leads{}
+	}
+	return c.LeadsMock
+}
+
+// Contacts implements amocrm.Client interface.
+func (c *Client) Contacts() amocrm.Contacts {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ContactsMock == nil {
+		c.ContactsMock = &Contacts{}
+	}
+	return c.ContactsMock
+}
+
+// Do implements amocrm.Client interface.
+func (c *Client) Do(method, path string, query url.Values, body, out interface{}) error {
+	return c.DoContext(context.Background(), method, path, query, body, out)
+}
+
+// DoContext implements amocrm.Client interface.
+func (c *Client) DoContext(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	c.record(ctx, "Do", method, path, query, body, out)
+	if c.DoFunc == nil {
+		return nil
+	}
+	return c.DoFunc(ctx, method, path, query, body, out)
+}
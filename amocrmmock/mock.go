@@ -0,0 +1,181 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package amocrmmock provides configurable mocks of amocrm.Client
+// and its repositories for unit tests that don't talk HTTP at all.
+//
+// Every mock method calls the corresponding Func field, if set, and
+// otherwise succeeds with empty results: empty entities, batch results
+// without IDs and iterators without items. Methods with and without context are
+// served by the same Func and recorded under the name without the
+// Context suffix, so assertions don't depend on which one was used.
+//
+//	leads := &amocrmmock.Leads{
+//		CreateFunc: func(ctx context.Context, leads []amocrm.Lead) (amocrm.BatchResult, error) {
+//			return amocrm.BatchResult{{ID: 1}}, nil
+//		},
+//	}
+//	client := &amocrmmock.Client{LeadsMock: leads}
+//
+//	// ... run the code under test with client
+//
+//	leads.AssertNumberOfCalls(t, "Create", 1)
+package amocrmmock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/alexeykhan/amocrm"
+)
+
+// TestingT is the subset of testing.TB used by assertions.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// Call is a recorded call of a mock method.
+type Call struct {
+	// Method is the name of the method without Context suffix.
+	Method string
+	// Ctx is the context the method was called with,
+	// context.Background for methods without context.
+	Ctx context.Context
+	// Args are arguments of the call except the context.
+	Args []interface{}
+}
+
+// Recorder records calls of mock methods. It is embedded
+// into every mock and is safe for concurrent use.
+type Recorder struct {
+	mu    sync.Mutex
+	calls []Call
+}
+
+// Calls returns all recorded calls in the order they were made.
+func (r *Recorder) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	calls := make([]Call, len(r.calls))
+	copy(calls, r.calls)
+	return calls
+}
+
+// CallsTo returns recorded calls of method.
+func (r *Recorder) CallsTo(method string) []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var calls []Call
+	for _, call := range r.calls {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// Reset forgets all recorded calls.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = nil
+}
+
+// AssertCalled checks that method was called at least once with args,
+// compared with reflect.DeepEqual. No args match any call of method.
+func (r *Recorder) AssertCalled(t TestingT, method string, args ...interface{}) bool {
+	t.Helper()
+
+	calls := r.CallsTo(method)
+	for _, call := range calls {
+		if len(args) == 0 || reflect.DeepEqual(call.Args, args) {
+			return true
+		}
+	}
+
+	if len(calls) == 0 {
+		t.Errorf("amocrmmock: expected call of %s, got none", method)
+	} else {
+		t.Errorf("amocrmmock: expected call of %s with %s, got %s", method, format(args), formatCalls(calls))
+	}
+	return false
+}
+
+// AssertNotCalled checks that method was never called.
+func (r *Recorder) AssertNotCalled(t TestingT, method string) bool {
+	t.Helper()
+
+	if calls := r.CallsTo(method); len(calls) > 0 {
+		t.Errorf("amocrmmock: unexpected call of %s: %s", method, formatCalls(calls))
+		return false
+	}
+	return true
+}
+
+// AssertNumberOfCalls checks that method was called exactly n times.
+func (r *Recorder) AssertNumberOfCalls(t TestingT, method string, n int) bool {
+	t.Helper()
+
+	if got := len(r.CallsTo(method)); got != n {
+		t.Errorf("amocrmmock: expected %d calls of %s, got %d", n, method, got)
+		return false
+	}
+	return true
+}
+
+func (r *Recorder) record(ctx context.Context, method string, args ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, Call{Method: method, Ctx: ctx, Args: args})
+}
+
+// Iterator returns amocrm.Iterator over items, which is a slice of
+// entities, e.g. []amocrm.Lead, for stubs of list methods. Non-nil
+// err is reported by the iterator once the items run out.
+func Iterator(items interface{}, err error) *amocrm.Iterator {
+	data, mErr := json.Marshal(items)
+	if mErr != nil {
+		return amocrm.NewStaticIterator(nil, fmt.Errorf("encode items: %w", mErr))
+	}
+
+	var raw []json.RawMessage
+	if uErr := json.Unmarshal(data, &raw); uErr != nil {
+		return amocrm.NewStaticIterator(nil, fmt.Errorf("encode items: %w", uErr))
+	}
+	return amocrm.NewStaticIterator(raw, err)
+}
+
+func format(args []interface{}) string {
+	return fmt.Sprintf("%+v", args)
+}
+
+func formatCalls(calls []Call) string {
+	args := make([]string, len(calls))
+	for i, call := range calls {
+		args[i] = format(call.Args)
+	}
+	return fmt.Sprintf("%v", args)
+}
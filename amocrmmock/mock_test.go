@@ -0,0 +1,157 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrmmock_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alexeykhan/amocrm"
+	"github.com/alexeykhan/amocrm/amocrmmock"
+)
+
+// fakeT records assertion failures instead of failing the test.
+type fakeT struct {
+	errors []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+// renameLeads is business logic under test.
+func renameLeads(ctx context.Context, client amocrm.Client, name string) (int, error) {
+	it := client.Leads().List(amocrm.LeadsConfig{Limit: 250})
+
+	var leads []amocrm.Lead
+	for it.Next(ctx) {
+		var lead amocrm.Lead
+		if err := it.Decode(&lead); err != nil {
+			return 0, err
+		}
+		leads = append(leads, amocrm.Lead{ID: lead.ID, Name: name})
+	}
+	if err := it.Err(); err != nil {
+		return 0, err
+	}
+
+	result, err := client.Leads().UpdateContext(ctx, leads)
+	return len(result) - len(result.Failed()), err
+}
+
+func TestClient(t *testing.T) {
+	leads := &amocrmmock.Leads{
+		ListFunc: func(cfg amocrm.LeadsConfig) *amocrm.Iterator {
+			return amocrmmock.Iterator([]amocrm.Lead{{ID: 1}, {ID: 2}}, nil)
+		},
+		UpdateFunc: func(ctx context.Context, leads []amocrm.Lead) (amocrm.BatchResult, error) {
+			return amocrm.BatchResult{{ID: leads[0].ID}, {ID: leads[1].ID}}, nil
+		},
+	}
+	client := &amocrmmock.Client{LeadsMock: leads}
+
+	ctx := context.WithValue(context.Background(), struct{}{}, "value")
+	n, err := renameLeads(ctx, client, "Renamed")
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	leads.AssertCalled(t, "List", amocrm.LeadsConfig{Limit: 250})
+	leads.AssertCalled(t, "Update", []amocrm.Lead{{ID: 1, Name: "Renamed"}, {ID: 2, Name: "Renamed"}})
+	leads.AssertNumberOfCalls(t, "Update", 1)
+	leads.AssertNotCalled(t, "Create")
+	require.Equal(t, ctx, leads.CallsTo("Update")[0].Ctx)
+	require.Len(t, leads.Calls(), 2)
+
+	leads.Reset()
+	require.Empty(t, leads.Calls())
+}
+
+func TestClient_Defaults(t *testing.T) {
+	client := &amocrmmock.Client{}
+
+	require.NoError(t, client.SetDomain("example.amocrm.ru"))
+	client.AssertCalled(t, "SetDomain", "example.amocrm.ru")
+
+	account, err := client.Accounts().Current(amocrm.AccountsConfig{})
+	require.NoError(t, err)
+	require.NotNil(t, account)
+	client.AccountsMock.AssertNumberOfCalls(t, "Current", 1)
+
+	result, err := client.Contacts().Create([]amocrm.Contact{{Name: "Contact"}})
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+
+	it := client.Contacts().List(amocrm.ContactsConfig{})
+	require.False(t, it.Next(context.Background()))
+	require.NoError(t, it.Err())
+
+	require.NoError(t, client.Do("GET", "leads/pipelines", nil, nil, nil))
+	client.AssertCalled(t, "Do", "GET", "leads/pipelines", url.Values(nil), nil, nil)
+}
+
+func TestClient_Do(t *testing.T) {
+	client := &amocrmmock.Client{
+		DoFunc: func(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+			return json.Unmarshal([]byte(`{"id":1}`), out)
+		},
+	}
+
+	var pipeline struct {
+		ID int `json:"id"`
+	}
+	require.NoError(t, client.DoContext(context.Background(), "GET", "leads/pipelines/1", nil, nil, &pipeline))
+	require.Equal(t, 1, pipeline.ID)
+	client.AssertNumberOfCalls(t, "Do", 1)
+}
+
+func TestIterator_Err(t *testing.T) {
+	errBoom := errors.New("boom")
+	it := amocrmmock.Iterator([]amocrm.Contact{{ID: 1}}, errBoom)
+
+	require.True(t, it.Next(context.Background()))
+	require.False(t, it.Next(context.Background()))
+	require.True(t, errors.Is(it.Err(), errBoom))
+
+	it = amocrmmock.Iterator(func() {}, nil)
+	require.False(t, it.Next(context.Background()))
+	require.Error(t, it.Err())
+}
+
+func TestRecorder_Failures(t *testing.T) {
+	leads := &amocrmmock.Leads{}
+	_, _ = leads.Create([]amocrm.Lead{{Name: "Lead"}})
+
+	ft := &fakeT{}
+	require.False(t, leads.AssertCalled(ft, "Update"))
+	require.False(t, leads.AssertCalled(ft, "Create", []amocrm.Lead{{Name: "Other"}}))
+	require.False(t, leads.AssertNotCalled(ft, "Create"))
+	require.False(t, leads.AssertNumberOfCalls(ft, "Create", 2))
+	require.Len(t, ft.errors, 4)
+	require.Contains(t, ft.errors[0], "expected call of Update, got none")
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrmmock
+
+import (
+	"context"
+
+	"github.com/alexeykhan/amocrm"
+)
+
+// Accounts is a mock of amocrm.Accounts.
+type Accounts struct {
+	Recorder
+
+	CurrentFunc func(ctx context.Context, cfg amocrm.AccountsConfig) (*amocrm.Account, error)
+}
+
+// Verify interface compliance.
+var _ amocrm.Accounts = (*Accounts)(nil)
+
+// Current implements amocrm.Accounts interface.
+func (a *Accounts) Current(cfg amocrm.AccountsConfig) (*amocrm.Account, error) {
+	return a.CurrentContext(context.Background(), cfg)
+}
+
+// CurrentContext implements amocrm.Accounts interface.
+func (a *Accounts) CurrentContext(ctx context.Context, cfg amocrm.AccountsConfig) (*amocrm.Account, error) {
+	a.record(ctx, "Current", cfg)
+	if a.CurrentFunc == nil {
+		return &amocrm.Account{}, nil
+	}
+	return a.CurrentFunc(ctx, cfg)
+}
+
+// Leads is a mock of amocrm.Leads. List returns an empty
+// iterator unless ListFunc is set, see Iterator.
+type Leads struct {
+	Recorder
+
+	ListFunc   func(cfg amocrm.LeadsConfig) *amocrm.Iterator
+	CreateFunc func(ctx context.Context, leads []amocrm.Lead) (amocrm.BatchResult, error)
+	UpdateFunc func(ctx context.Context, leads []amocrm.Lead) (amocrm.BatchResult, error)
+}
+
+// Verify interface compliance.
+var _ amocrm.Leads = (*Leads)(nil)
+
+// List implements amocrm.Leads interface.
+func (l *Leads) List(cfg amocrm.LeadsConfig) *amocrm.Iterator {
+	l.record(context.Background(), "List", cfg)
+	if l.ListFunc == nil {
+		return amocrm.NewStaticIterator(nil, nil)
+	}
+	return l.ListFunc(cfg)
+}
+
+// Create implements amocrm.Leads interface.
+func (l *Leads) Create(leads []amocrm.Lead) (amocrm.BatchResult, error) {
+	return l.CreateContext(context.Background(), leads)
+}
+
+// CreateContext implements amocrm.Leads interface.
+func (l *Leads) CreateContext(ctx context.Context, leads []amocrm.Lead) (amocrm.BatchResult, error) {
+	l.record(ctx, "Create", leads)
+	if l.CreateFunc == nil {
+		return make(amocrm.BatchResult, len(leads)), nil
+	}
+	return l.CreateFunc(ctx, leads)
+}
+
+// Update implements amocrm.Leads interface.
+func (l *Leads) Update(leads []amocrm.Lead) (amocrm.BatchResult, error) {
+	return l.UpdateContext(context.Background(), leads)
+}
+
+// UpdateContext implements amocrm.Leads interface.
+func (l *Leads) UpdateContext(ctx context.Context, leads []amocrm.Lead) (amocrm.BatchResult, error) {
+	l.record(ctx, "Update", leads)
+	if l.UpdateFunc == nil {
+		return make(amocrm.BatchResult, len(leads)), nil
+	}
+	return l.UpdateFunc(ctx, leads)
+}
+
+// Contacts is a mock of amocrm.Contacts. List returns an empty
+// iterator unless ListFunc is set, see Iterator.
+type Contacts struct {
+	Recorder
+
+	ListFunc   func(cfg amocrm.ContactsConfig) *amocrm.Iterator
+	CreateFunc func(ctx context.Context, contacts []amocrm.Contact) (amocrm.BatchResult, error)
+	UpdateFunc func(ctx context.Context, contacts []amocrm.Contact) (amocrm.BatchResult, error)
+}
+
+// Verify interface compliance.
+var _ amocrm.Contacts = (*Contacts)(nil)
+
+// List implements amocrm.Contacts interface.
+func (c *Contacts) List(cfg amocrm.ContactsConfig) *amocrm.Iterator {
+	c.record(context.Background(), "List", cfg)
+	if c.ListFunc == nil {
+		return amocrm.NewStaticIterator(nil, nil)
+	}
+	return c.ListFunc(cfg)
+}
+
+// Create implements amocrm.Contacts interface.
+func (c *Contacts) Create(contacts []amocrm.Contact) (amocrm.BatchResult, error) {
+	return c.CreateContext(context.Background(), contacts)
+}
+
+// CreateContext implements amocrm.Contacts interface.
+func (c *Contacts) CreateContext(ctx context.Context, contacts []amocrm.Contact) (amocrm.BatchResult, error) {
+	c.record(ctx, "Create", contacts)
+	if c.CreateFunc == nil {
+		return make(amocrm.BatchResult, len(contacts)), nil
+	}
+	return c.CreateFunc(ctx, contacts)
+}
+
+// Update implements amocrm.Contacts interface.
+func (c *Contacts) Update(contacts []amocrm.Contact) (amocrm.BatchResult, error) {
+	return c.UpdateContext(context.Background(), contacts)
+}
+
+// UpdateContext implements amocrm.Contacts interface.
+func (c *Contacts) UpdateContext(ctx context.Context, contacts []amocrm.Contact) (amocrm.BatchResult, error) {
+	c.record(ctx, "Update", contacts)
+	if c.UpdateFunc == nil {
+		return make(amocrm.BatchResult, len(contacts)), nil
+	}
+	return c.UpdateFunc(ctx, contacts)
+}
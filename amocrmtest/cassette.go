@@ -0,0 +1,351 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrmtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// redacted replaces secrets in recorded interactions.
+const redacted = "[REDACTED]"
+
+// Headers and fields of form and JSON bodies that are always
+// scrubbed from recorded interactions.
+var (
+	secretHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+	secretFields  = []string{"client_secret", "code", "access_token", "refresh_token"}
+)
+
+// Cassette is a set of recorded HTTP interactions stored in a fixture file.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request and the response to it.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a request of Interaction.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is a response of Interaction.
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// LoadCassette reads Cassette from the fixture file at path.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read cassette: %w", err)
+	}
+
+	c := &Cassette{}
+	if err = json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("decode cassette: %w", err)
+	}
+	return c, nil
+}
+
+// Save writes Cassette to the fixture file at path,
+// creating its directory if needed.
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode cassette: %w", err)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create cassette directory: %w", err)
+	}
+	if err = ioutil.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write cassette: %w", err)
+	}
+	return nil
+}
+
+// Recorder is http.RoundTripper that sends requests through another
+// RoundTripper and records them along with responses. Authorization
+// headers, cookies, client secret, authorization code and tokens are
+// scrubbed, as well as any extra secrets Recorder is created with.
+// Pass it to amocrm.WithTransport and call Save when done.
+type Recorder struct {
+	path      string
+	transport http.RoundTripper
+	secrets   []string
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// Verify interface compliance.
+var _ http.RoundTripper = (*Recorder)(nil)
+
+// NewRecorder returns Recorder writing to the fixture file at path.
+// Nil transport means http.DefaultTransport.
+func NewRecorder(path string, transport http.RoundTripper, secrets ...string) *Recorder {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &Recorder{path: path, transport: transport, secrets: secrets}
+}
+
+// RoundTrip implements http.RoundTripper interface.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("amocrmtest: read request body: %w", err)
+	}
+
+	out := req.Clone(req.Context())
+	if req.Body != nil {
+		out.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	resp, err := r.transport.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := readBody(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("amocrmtest: read response body: %w", err)
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	interaction := Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    r.scrub(req.URL.String()),
+			Header: r.scrubHeader(req.Header),
+			Body:   r.scrubBody(reqBody, req.Header),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     r.scrubHeader(resp.Header),
+			Body:       r.scrubBody(respBody, resp.Header),
+		},
+	}
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	r.mu.Unlock()
+
+	return resp, nil
+}
+
+// Save writes recorded interactions to the fixture file.
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cassette.Save(r.path)
+}
+
+func (r *Recorder) scrub(s string) string {
+	for _, secret := range r.secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, redacted)
+		}
+	}
+	return s
+}
+
+func (r *Recorder) scrubHeader(header http.Header) http.Header {
+	scrubbed := make(http.Header, len(header))
+	for k, values := range header {
+		for _, v := range values {
+			scrubbed.Add(k, r.scrub(v))
+		}
+	}
+	for _, k := range secretHeaders {
+		if _, ok := scrubbed[k]; ok {
+			scrubbed.Set(k, redacted)
+		}
+	}
+	return scrubbed
+}
+
+func (r *Recorder) scrubBody(body []byte, header http.Header) string {
+	return r.scrub(string(scrubFields(body, header.Get("Content-Type"))))
+}
+
+// scrubFields replaces values of secret fields in JSON object or
+// form body. Bodies without secret fields are returned unchanged.
+func scrubFields(body []byte, contentType string) []byte {
+	switch {
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return body
+		}
+
+		var found bool
+		for _, field := range secretFields {
+			if _, ok := form[field]; ok {
+				form.Set(field, redacted)
+				found = true
+			}
+		}
+		if !found {
+			return body
+		}
+		return []byte(form.Encode())
+
+	case strings.Contains(contentType, "json"):
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(body, &object); err != nil {
+			return body
+		}
+
+		var found bool
+		for _, field := range secretFields {
+			if _, ok := object[field]; ok {
+				object[field] = json.RawMessage(`"` + redacted + `"`)
+				found = true
+			}
+		}
+		if !found {
+			return body
+		}
+
+		scrubbed, err := json.Marshal(object)
+		if err != nil {
+			return body
+		}
+		return scrubbed
+	}
+
+	return body
+}
+
+// Replayer is http.RoundTripper that serves responses from a fixture
+// file written by Recorder instead of sending requests. Requests are
+// matched to interactions by method and URL, each interaction is served
+// once in the recorded order. Unexpected requests fail with an error.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// Verify interface compliance.
+var _ http.RoundTripper = (*Replayer)(nil)
+
+// NewReplayer returns Replayer serving the fixture file at path.
+func NewReplayer(path string) (*Replayer, error) {
+	c, err := LoadCassette(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Replayer{
+		interactions: c.Interactions,
+		used:         make([]bool, len(c.Interactions)),
+	}, nil
+}
+
+// RoundTrip implements http.RoundTripper interface.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, interaction := range r.interactions {
+		if r.used[i] || !matches(interaction.Request, req) {
+			continue
+		}
+		r.used[i] = true
+
+		resp := interaction.Response
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode)),
+			StatusCode:    resp.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        resp.Header.Clone(),
+			Body:          ioutil.NopCloser(strings.NewReader(resp.Body)),
+			ContentLength: int64(len(resp.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("amocrmtest: unexpected request %s %s", req.Method, req.URL)
+}
+
+// Unused returns interactions that were not replayed yet.
+func (r *Replayer) Unused() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var unused []Interaction
+	for i, interaction := range r.interactions {
+		if !r.used[i] {
+			unused = append(unused, interaction)
+		}
+	}
+	return unused
+}
+
+func matches(recorded RecordedRequest, req *http.Request) bool {
+	if recorded.Method != req.Method {
+		return false
+	}
+
+	u, err := url.Parse(recorded.URL)
+	if err != nil {
+		return false
+	}
+	return u.Scheme == req.URL.Scheme && u.Host == req.URL.Host &&
+		u.Path == req.URL.Path && u.Query().Encode() == req.URL.Query().Encode()
+}
+
+// readBody reads and closes body, which may be nil.
+func readBody(body io.ReadCloser) ([]byte, error) {
+	if body == nil {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadAll(body)
+	_ = body.Close()
+	return data, err
+}
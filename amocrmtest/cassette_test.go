@@ -0,0 +1,91 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrmtest_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alexeykhan/amocrm"
+	"github.com/alexeykhan/amocrm/amocrmtest"
+)
+
+func TestCassette(t *testing.T) {
+	const clientSecret = "super-secret-value"
+	path := filepath.Join(t.TempDir(), "fixtures", "account.json")
+
+	srv := amocrmtest.NewServer()
+	srv.SetAccount(amocrm.Account{ID: 42, Name: "Recorded"})
+
+	// Record a real conversation, including the token exchange.
+	rec := amocrmtest.NewRecorder(path, nil, clientSecret)
+	cl := amocrm.New("client_id", clientSecret, "https://example.com/oauth",
+		srv.Option(), amocrm.WithTransport(rec), amocrm.WithLimiter(nil))
+	require.NoError(t, cl.SetDomain(amocrmtest.Domain))
+
+	token, err := cl.TokenByCode("authorization-code")
+	require.NoError(t, err)
+	require.NoError(t, cl.SetToken(token))
+
+	account, err := cl.Accounts().Current(amocrm.AccountsConfig{})
+	require.NoError(t, err)
+	require.Equal(t, 42, account.ID)
+	require.NoError(t, rec.Save())
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	for _, secret := range []string{clientSecret, "authorization-code", token.AccessToken(), token.RefreshToken()} {
+		require.NotContains(t, string(data), secret)
+	}
+
+	// Replay it with the server gone.
+	srv.Close()
+
+	replayer, err := amocrmtest.NewReplayer(path)
+	require.NoError(t, err)
+
+	cl = amocrm.New("client_id", "another-secret", "https://example.com/oauth",
+		srv.Option(), amocrm.WithTransport(replayer), amocrm.WithLimiter(nil))
+	require.NoError(t, cl.SetDomain(amocrmtest.Domain))
+
+	token, err = cl.TokenByCode("another-code")
+	require.NoError(t, err)
+	require.NoError(t, cl.SetToken(token))
+
+	account, err = cl.Accounts().Current(amocrm.AccountsConfig{})
+	require.NoError(t, err)
+	require.Equal(t, 42, account.ID)
+	require.Equal(t, "Recorded", account.Name)
+	require.Empty(t, replayer.Unused())
+
+	// Every interaction is served once.
+	_, err = cl.Accounts().Current(amocrm.AccountsConfig{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unexpected request GET")
+}
+
+func TestNewReplayer_MissingFile(t *testing.T) {
+	_, err := amocrmtest.NewReplayer(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
@@ -0,0 +1,284 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrmtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 250
+)
+
+type object map[string]json.RawMessage
+
+// collection is an in-memory store of entities of one kind.
+type collection struct {
+	entity string
+	nextID int
+	items  map[int]object
+}
+
+func newCollection(entity string) *collection {
+	return &collection{entity: entity, nextID: 1, items: make(map[int]object)}
+}
+
+func (c *collection) path() string {
+	return apiPrefix + c.entity
+}
+
+// insert stores entities in body, which is a JSON array,
+// assigning them new IDs, and returns the IDs.
+func (c *collection) insert(body []byte) ([]int, error) {
+	var items []object
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, err
+	}
+
+	now := mustMarshal(time.Now().Unix())
+	ids := make([]int, 0, len(items))
+	for _, item := range items {
+		id := c.nextID
+		c.nextID++
+
+		delete(item, "request_id")
+		delete(item, "_links")
+		item["id"] = mustMarshal(id)
+		if _, ok := item["created_at"]; !ok {
+			item["created_at"] = now
+		}
+		item["updated_at"] = now
+
+		c.items[id] = item
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// all returns stored entities ordered by ID.
+func (c *collection) all() []object {
+	ids := make([]int, 0, len(c.items))
+	for id := range c.items {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	items := make([]object, 0, len(ids))
+	for _, id := range ids {
+		item := make(object, len(c.items[id])+1)
+		for k, v := range c.items[id] {
+			item[k] = v
+		}
+		item["_links"] = c.links(id)
+		items = append(items, item)
+	}
+	return items
+}
+
+func (c *collection) links(id int) json.RawMessage {
+	return mustMarshal(map[string]interface{}{
+		"self": map[string]string{"href": fmt.Sprintf("%s/%d", c.path(), id)},
+	})
+}
+
+func (c *collection) list(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit, err := intParam(query, "limit", defaultLimit)
+	if err != nil || limit < 1 || limit > maxLimit {
+		writeProblem(w, http.StatusBadRequest, "invalid limit")
+		return
+	}
+	page, err := intParam(query, "page", 1)
+	if err != nil || page < 1 {
+		writeProblem(w, http.StatusBadRequest, "invalid page")
+		return
+	}
+
+	items := c.all()
+	if ids, ok := query["filter[id][]"]; ok {
+		items = filterIDs(items, ids)
+	}
+
+	from := (page - 1) * limit
+	if from >= len(items) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	to := from + limit
+	if to > len(items) {
+		to = len(items)
+	}
+
+	links := map[string]interface{}{
+		"self": map[string]string{"href": c.pageHref(query, page)},
+	}
+	if to < len(items) {
+		links["next"] = map[string]string{"href": c.pageHref(query, page+1)}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"_page":     page,
+		"_links":    links,
+		"_embedded": map[string]interface{}{c.entity: items[from:to]},
+	})
+}
+
+func (c *collection) pageHref(query url.Values, page int) string {
+	q := url.Values{}
+	for k, v := range query {
+		q[k] = v
+	}
+	q.Set("page", strconv.Itoa(page))
+	return c.path() + "?" + q.Encode()
+}
+
+func (c *collection) create(w http.ResponseWriter, body []byte) {
+	var items []object
+	if err := json.Unmarshal(body, &items); err != nil || len(items) == 0 {
+		writeProblem(w, http.StatusBadRequest, "request body must be a non-empty array")
+		return
+	}
+
+	ids, err := c.insert(body)
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result := make([]map[string]interface{}, 0, len(ids))
+	for i, id := range ids {
+		result = append(result, map[string]interface{}{
+			"id":         id,
+			"request_id": requestID(items[i], i),
+			"_links":     c.links(id),
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"_links":    map[string]interface{}{"self": map[string]string{"href": c.path()}},
+		"_embedded": map[string]interface{}{c.entity: result},
+	})
+}
+
+// update applies changes in body to stored entities. Like amoCRM,
+// it rejects the whole request if any entity is not found.
+func (c *collection) update(w http.ResponseWriter, body []byte) {
+	var items []object
+	if err := json.Unmarshal(body, &items); err != nil || len(items) == 0 {
+		writeProblem(w, http.StatusBadRequest, "request body must be a non-empty array")
+		return
+	}
+
+	ids := make([]int, len(items))
+	var invalid []map[string]interface{}
+	for i, item := range items {
+		_ = json.Unmarshal(item["id"], &ids[i])
+		if _, ok := c.items[ids[i]]; !ok {
+			invalid = append(invalid, map[string]interface{}{
+				"request_id": requestID(item, i),
+				"errors": []map[string]string{{
+					"code":   "NotFound",
+					"path":   "id",
+					"detail": fmt.Sprintf("%s entity not found", c.entity),
+				}},
+			})
+		}
+	}
+	if len(invalid) > 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"title":             "Bad Request",
+			"type":              "https://httpstatus.es/400",
+			"status":            http.StatusBadRequest,
+			"detail":            "Request validation failed",
+			"validation-errors": invalid,
+		})
+		return
+	}
+
+	now := mustMarshal(time.Now().Unix())
+	result := make([]map[string]interface{}, 0, len(items))
+	for i, item := range items {
+		stored := c.items[ids[i]]
+		for k, v := range item {
+			if k != "id" && k != "request_id" && k != "_links" {
+				stored[k] = v
+			}
+		}
+		stored["updated_at"] = now
+
+		result = append(result, map[string]interface{}{
+			"id":         ids[i],
+			"updated_at": stored["updated_at"],
+			"request_id": requestID(item, i),
+			"_links":     c.links(ids[i]),
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"_links":    map[string]interface{}{"self": map[string]string{"href": c.path()}},
+		"_embedded": map[string]interface{}{c.entity: result},
+	})
+}
+
+func filterIDs(items []object, ids []string) []object {
+	want := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		want[id] = true
+	}
+
+	var filtered []object
+	for _, item := range items {
+		if want[string(item["id"])] {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+// requestID returns request_id of item, or its index if there is none.
+func requestID(item object, i int) string {
+	var id string
+	if err := json.Unmarshal(item["request_id"], &id); err != nil || id == "" {
+		return strconv.Itoa(i)
+	}
+	return id
+}
+
+func intParam(query url.Values, key string, def int) (int, error) {
+	if query.Get(key) == "" {
+		return def, nil
+	}
+	return strconv.Atoi(query.Get(key))
+}
+
+func mustMarshal(v interface{}) json.RawMessage {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
@@ -0,0 +1,408 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package amocrmtest provides an in-memory fake amoCRM server
+// for integration tests of code built on top of amocrm package.
+//
+//	srv := amocrmtest.NewServer()
+//	defer srv.Close()
+//
+//	client := amocrm.New(clientID, clientSecret, redirectURL, srv.Option())
+//	_ = client.SetDomain(amocrmtest.Domain)
+//	_ = client.SetToken(srv.Token())
+package amocrmtest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexeykhan/amocrm"
+)
+
+// Domain is the account domain to set on clients of the server.
+// Requests go to the server regardless of the domain.
+const Domain = "example.amocrm.ru"
+
+// DefaultTokenTTL is the lifetime of access tokens issued by the server.
+const DefaultTokenTTL = 24 * time.Hour
+
+const (
+	tokenPath   = "/oauth2/access_token"
+	apiPrefix   = "/api/v4/"
+	accountPath = apiPrefix + "account"
+)
+
+// Request is a request received by the server.
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+}
+
+// Failure is a scripted failure the server responds with instead of
+// handling matching requests, e.g. Failure{Status: 429, Times: 2}.
+type Failure struct {
+	// Method of requests to fail, empty matches any method.
+	Method string
+	// Path of requests to fail, e.g. "/api/v4/leads".
+	// Empty matches any path.
+	Path string
+	// Status is the response status code.
+	Status int
+	// RetryAfter sets Retry-After header of the response, if positive.
+	RetryAfter time.Duration
+	// Times is the number of requests to fail, one if not positive.
+	Times int
+}
+
+// Server is a fake amoCRM server backed by in-memory state. It issues
+// OAuth2 tokens, serves the account and leads and contacts endpoints,
+// and records requests it receives. Server is safe for concurrent use.
+type Server struct {
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	ttl      time.Duration
+	access   map[string]time.Time
+	refresh  map[string]bool
+	account  amocrm.Account
+	entities map[string]*collection
+	failures []Failure
+	requests []Request
+}
+
+// NewServer starts and returns a new Server. Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		ttl:     DefaultTokenTTL,
+		access:  make(map[string]time.Time),
+		refresh: make(map[string]bool),
+		account: amocrm.Account{
+			ID:        1,
+			Name:      "Test account",
+			Subdomain: strings.Split(Domain, ".")[0],
+			Country:   "RU",
+			Currency:  "RUB",
+		},
+		entities: map[string]*collection{
+			"leads":    newCollection("leads"),
+			"contacts": newCollection("contacts"),
+		},
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Close shuts down the server.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// URL returns base URL of the server.
+func (s *Server) URL() string {
+	return s.srv.URL
+}
+
+// Option returns amocrm.Option that sends all requests of a client,
+// including token requests, to the server.
+func (s *Server) Option() amocrm.Option {
+	return amocrm.WithBaseURL(s.srv.URL)
+}
+
+// SetAccount sets the account returned by the account endpoint.
+func (s *Server) SetAccount(account amocrm.Account) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.account = account
+}
+
+// SetTokenTTL sets the lifetime of access tokens issued from now on.
+func (s *Server) SetTokenTTL(ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ttl = ttl
+}
+
+// Token issues a new valid token, as if obtained by authorization code.
+func (s *Server) Token() amocrm.Token {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.issue()
+}
+
+// ExpireTokens makes all issued access tokens expired, so requests
+// with them are rejected with 401 until the token is refreshed.
+// Refresh tokens stay valid.
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expired := time.Now().Add(-time.Second)
+	for token := range s.access {
+		s.access[token] = expired
+	}
+}
+
+// Fail scripts failures of the next matching requests. Failures
+// are matched in the order they were added.
+func (s *Server) Fail(failures ...Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, f := range failures {
+		if f.Times <= 0 {
+			f.Times = 1
+		}
+		s.failures = append(s.failures, f)
+	}
+}
+
+// Requests returns requests received by the server so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	requests := make([]Request, len(s.requests))
+	copy(requests, s.requests)
+	return requests
+}
+
+// Leads returns leads stored on the server ordered by ID.
+func (s *Server) Leads() []amocrm.Lead {
+	var leads []amocrm.Lead
+	s.decodeAll("leads", &leads)
+	return leads
+}
+
+// Contacts returns contacts stored on the server ordered by ID.
+func (s *Server) Contacts() []amocrm.Contact {
+	var contacts []amocrm.Contact
+	s.decodeAll("contacts", &contacts)
+	return contacts
+}
+
+// AddLeads stores leads on the server and returns their IDs.
+func (s *Server) AddLeads(leads ...amocrm.Lead) []int {
+	return s.add("leads", leads)
+}
+
+// AddContacts stores contacts on the server and returns their IDs.
+func (s *Server) AddContacts(contacts ...amocrm.Contact) []int {
+	return s.add("contacts", contacts)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, Request{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.Query(),
+		Header: r.Header.Clone(),
+		Body:   body,
+	})
+
+	if f, ok := s.failure(r); ok {
+		if f.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int((f.RetryAfter+time.Second-1)/time.Second)))
+		}
+		writeProblem(w, f.Status, "scripted failure")
+		return
+	}
+
+	if r.URL.Path == tokenPath {
+		s.serveToken(w, r, body)
+		return
+	}
+
+	if !strings.HasPrefix(r.URL.Path, apiPrefix) {
+		writeProblem(w, http.StatusNotFound, "unknown endpoint")
+		return
+	}
+	if !s.authorized(r) {
+		writeProblem(w, http.StatusUnauthorized, "invalid or expired access token")
+		return
+	}
+
+	if r.URL.Path == accountPath {
+		if r.Method != http.MethodGet {
+			writeProblem(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		writeJSON(w, http.StatusOK, s.account)
+		return
+	}
+
+	c, ok := s.entities[strings.TrimPrefix(r.URL.Path, apiPrefix)]
+	if !ok {
+		writeProblem(w, http.StatusNotFound, "unknown endpoint")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		c.list(w, r)
+	case http.MethodPost:
+		c.create(w, body)
+	case http.MethodPatch:
+		c.update(w, body)
+	default:
+		writeProblem(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// failure returns the first scripted failure matching r, if any.
+func (s *Server) failure(r *http.Request) (Failure, bool) {
+	for i, f := range s.failures {
+		if (f.Method != "" && f.Method != r.Method) || (f.Path != "" && f.Path != r.URL.Path) {
+			continue
+		}
+
+		s.failures[i].Times--
+		if s.failures[i].Times == 0 {
+			s.failures = append(s.failures[:i], s.failures[i+1:]...)
+		}
+		return f, true
+	}
+	return Failure{}, false
+}
+
+func (s *Server) serveToken(w http.ResponseWriter, r *http.Request, body []byte) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, "invalid form")
+		return
+	}
+
+	switch form.Get("grant_type") {
+	case "authorization_code":
+		if form.Get("code") == "" {
+			writeProblem(w, http.StatusBadRequest, "missing authorization code")
+			return
+		}
+	case "refresh_token":
+		if !s.refresh[form.Get("refresh_token")] {
+			writeProblem(w, http.StatusBadRequest, "invalid refresh token")
+			return
+		}
+		delete(s.refresh, form.Get("refresh_token"))
+	default:
+		writeProblem(w, http.StatusBadRequest, "unsupported grant type")
+		return
+	}
+
+	token := s.issue()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"token_type":    token.TokenType(),
+		"expires_in":    int(s.ttl / time.Second),
+		"access_token":  token.AccessToken(),
+		"refresh_token": token.RefreshToken(),
+	})
+}
+
+// issue creates a new token pair, s.mu must be held.
+func (s *Server) issue() amocrm.Token {
+	access, refresh := randomString(), randomString()
+	expiresAt := time.Now().Add(s.ttl)
+
+	s.access[access] = expiresAt
+	s.refresh[refresh] = true
+
+	return amocrm.NewToken(access, refresh, "Bearer", expiresAt)
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	expiresAt, ok := s.access[token]
+	return ok && time.Now().Before(expiresAt)
+}
+
+func (s *Server) add(entity string, items interface{}) []int {
+	body, err := json.Marshal(items)
+	if err != nil {
+		panic(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids, err := s.entities[entity].insert(body)
+	if err != nil {
+		panic(err)
+	}
+	return ids
+}
+
+func (s *Server) decodeAll(entity string, v interface{}) {
+	s.mu.Lock()
+	body, err := json.Marshal(s.entities[entity].all())
+	s.mu.Unlock()
+
+	if err == nil {
+		err = json.Unmarshal(body, v)
+	}
+	if err != nil {
+		panic(err)
+	}
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/hal+json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeProblem(w http.ResponseWriter, status int, detail string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"title":  http.StatusText(status),
+		"type":   "https://httpstatus.es/" + strconv.Itoa(status),
+		"status": status,
+		"detail": detail,
+	})
+}
@@ -0,0 +1,185 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrmtest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alexeykhan/amocrm"
+	"github.com/alexeykhan/amocrm/amocrmtest"
+)
+
+func newClient(t *testing.T, srv *amocrmtest.Server, opts ...amocrm.Option) amocrm.Client {
+	t.Helper()
+
+	opts = append([]amocrm.Option{srv.Option(), amocrm.WithLimiter(nil)}, opts...)
+	cl := amocrm.New("client_id", "client_secret", "https://example.com/oauth", opts...)
+	require.NoError(t, cl.SetDomain(amocrmtest.Domain))
+	return cl
+}
+
+func TestServer_Account(t *testing.T) {
+	srv := amocrmtest.NewServer()
+	defer srv.Close()
+	srv.SetAccount(amocrm.Account{ID: 42, Name: "Acme"})
+
+	cl := newClient(t, srv)
+	_, err := cl.Accounts().Current(amocrm.AccountsConfig{})
+	require.Error(t, err)
+
+	token, err := cl.TokenByCode("code")
+	require.NoError(t, err)
+	require.NoError(t, cl.SetToken(token))
+
+	account, err := cl.Accounts().Current(amocrm.AccountsConfig{})
+	require.NoError(t, err)
+	require.Equal(t, 42, account.ID)
+	require.Equal(t, "Acme", account.Name)
+
+	requests := srv.Requests()
+	require.Len(t, requests, 2)
+	require.Equal(t, "/oauth2/access_token", requests[0].Path)
+	require.Equal(t, http.MethodGet, requests[1].Method)
+	require.Equal(t, "/api/v4/account", requests[1].Path)
+	require.Contains(t, requests[1].Header.Get("Authorization"), "Bearer ")
+}
+
+func TestServer_TokenExpiry(t *testing.T) {
+	srv := amocrmtest.NewServer()
+	defer srv.Close()
+
+	cl := newClient(t, srv)
+	token := srv.Token()
+	require.NoError(t, cl.SetToken(token))
+
+	// Revoked token is refreshed on 401 and the request is retried.
+	srv.ExpireTokens()
+	_, err := cl.Accounts().Current(amocrm.AccountsConfig{})
+	require.NoError(t, err)
+
+	requests := srv.Requests()
+	require.Len(t, requests, 3)
+	require.Equal(t, "/oauth2/access_token", requests[1].Path)
+	require.Equal(t, "/api/v4/account", requests[2].Path)
+
+	// Revoked refresh token means the user must authorize again.
+	srv.ExpireTokens()
+	srv.Fail(amocrmtest.Failure{Path: "/oauth2/access_token", Status: http.StatusUnauthorized})
+	_, err = cl.Accounts().Current(amocrm.AccountsConfig{})
+	require.True(t, amocrm.IsUnauthorized(err))
+	require.True(t, amocrm.IsReauthRequired(err))
+
+	token = srv.Token()
+	expired := amocrm.NewToken(token.AccessToken(), token.RefreshToken(), token.TokenType(), time.Now().Add(-time.Minute))
+	require.NoError(t, cl.SetToken(expired))
+
+	_, err = cl.Accounts().Current(amocrm.AccountsConfig{})
+	require.NoError(t, err)
+
+	requests = srv.Requests()
+	require.Equal(t, "/oauth2/access_token", requests[len(requests)-2].Path)
+
+	// Refresh tokens are single-use.
+	require.NoError(t, cl.SetToken(expired))
+	_, err = cl.Accounts().Current(amocrm.AccountsConfig{})
+	require.Error(t, err)
+}
+
+func TestServer_Fail(t *testing.T) {
+	srv := amocrmtest.NewServer()
+	defer srv.Close()
+
+	cl := newClient(t, srv, amocrm.WithRetryPolicy(amocrm.RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond}))
+	require.NoError(t, cl.SetToken(srv.Token()))
+
+	srv.Fail(
+		amocrmtest.Failure{Path: "/api/v4/account", Status: http.StatusServiceUnavailable, Times: 2},
+		amocrmtest.Failure{Method: http.MethodPost, Status: http.StatusTooManyRequests, RetryAfter: time.Minute},
+	)
+
+	_, err := cl.Accounts().Current(amocrm.AccountsConfig{})
+	require.NoError(t, err)
+	require.Len(t, srv.Requests(), 3)
+
+	cl = newClient(t, srv)
+	require.NoError(t, cl.SetToken(srv.Token()))
+
+	_, err = cl.Leads().Create([]amocrm.Lead{{Name: "Lead"}})
+	var apiErr *amocrm.APIError
+	require.True(t, errors.As(err, &apiErr))
+	require.Equal(t, http.StatusTooManyRequests, apiErr.StatusCode)
+	require.Equal(t, time.Minute, apiErr.RetryAfter)
+	require.Empty(t, srv.Leads())
+}
+
+func TestServer_Entities(t *testing.T) {
+	srv := amocrmtest.NewServer()
+	defer srv.Close()
+
+	cl := newClient(t, srv)
+	require.NoError(t, cl.SetToken(srv.Token()))
+
+	ids := srv.AddContacts(amocrm.Contact{Name: "Seeded"})
+	require.Equal(t, []int{1}, ids)
+
+	leads := make([]amocrm.Lead, 5)
+	for i := range leads {
+		leads[i].Name = "Lead"
+		leads[i].Price = i
+	}
+	result, err := cl.Leads().Create(leads)
+	require.NoError(t, err)
+	require.Equal(t, 5, result[4].ID)
+
+	result, err = cl.Leads().Update([]amocrm.Lead{{ID: 2, Name: "Updated"}, {ID: 404, Name: "Missing"}})
+	require.Error(t, err)
+	require.Equal(t, []int{0, 1}, result.Failed())
+	require.True(t, amocrm.IsValidation(result[1].Err))
+
+	_, err = cl.Leads().Update([]amocrm.Lead{{ID: 2, Name: "Updated"}})
+	require.NoError(t, err)
+
+	it := cl.Leads().List(amocrm.LeadsConfig{Limit: 2})
+	var names []string
+	for it.Next(context.Background()) {
+		var lead amocrm.Lead
+		require.NoError(t, it.Decode(&lead))
+		names = append(names, lead.Name)
+	}
+	require.NoError(t, it.Err())
+	require.Equal(t, []string{"Lead", "Updated", "Lead", "Lead", "Lead"}, names)
+
+	it = cl.Contacts().List(amocrm.ContactsConfig{Filter: amocrm.NewFilter().IDs(404)})
+	require.False(t, it.Next(context.Background()))
+	require.NoError(t, it.Err())
+
+	stored := srv.Leads()
+	require.Len(t, stored, 5)
+	require.Equal(t, 3, stored[3].Price)
+	require.False(t, stored[0].CreatedAt.IsZero())
+	require.Equal(t, "Seeded", srv.Contacts()[0].Name)
+}
@@ -23,6 +23,8 @@
 package amocrm
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -31,6 +33,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -66,81 +69,398 @@ var (
 )
 
 const (
+	tokenPath      = "/oauth2/access_token"
 	userAgent      = "AmoCRM-API-Golang-Client"
 	apiVersion     = uint8(4)
 	requestTimeout = 20 * time.Second
+	refreshTimeout = requestTimeout
 )
 
 // api implements Client interface.
 type api struct {
+	settings
+
+	// mu guards domain, token and refresh that may be
+	// accessed by goroutines sharing the client.
+	mu      sync.RWMutex
+	domain  string
+	token   Token
+	refresh *refreshCall
+
+	// fixedDomain forbids changing domain of pooled clients.
+	fixedDomain bool
+	handler     Handler
+}
+
+// settings holds configuration shared by clients of a pool.
+type settings struct {
 	clientID     string
 	clientSecret string
 	redirectURL  string
 
-	domain string
-	token  Token
-
-	http *http.Client
+	http      *http.Client
+	limiter   Limiter
+	retry     RetryPolicy
+	store     TokenStore
+	onRefresh func(domain string, token Token)
+	logger    Logger
+	metrics   Metrics
+	plan      *Plan
+
+	middlewares []Middleware
+	userAgent   string
+	baseURL     string
+	authURL     string
+	regions     []Region
 }
 
-func newAPI(clientID, clientSecret, redirectURL string) *api {
-	return &api{
-		clientID:     clientID,
-		clientSecret: clientSecret,
-		redirectURL:  redirectURL,
-		http: &http.Client{
-			Timeout: requestTimeout,
+func newAPI(clientID, clientSecret, redirectURL string, opts ...Option) *api {
+	a := &api{
+		settings: settings{
+			clientID:     clientID,
+			clientSecret: clientSecret,
+			redirectURL:  redirectURL,
+			http: &http.Client{
+				Timeout: requestTimeout,
+			},
+			limiter:   NewLimiter(defaultLimiterRPS, defaultLimiterBurst),
+			userAgent: userAgent,
+			regions:   defaultRegions,
 		},
 	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	a.handler = chain(a.roundTrip, a.middlewares)
+
+	return a
 }
 
-func (a *api) get(ep endpoint, q url.Values, h http.Header) (*http.Response, error) {
-	if a.token == nil {
-		return nil, errors.New("invalid token")
+// clone returns a new api sharing settings with a,
+// but having its own domain and token.
+func (a *api) clone() *api {
+	c := &api{settings: a.settings}
+	c.handler = chain(c.roundTrip, c.middlewares)
+	return c
+}
+
+func (a *api) get(ctx context.Context, ep endpoint, q url.Values, h http.Header) (*http.Response, error) {
+	return a.do(ctx, http.MethodGet, ep, q, nil, h)
+}
+
+func (a *api) post(ctx context.Context, ep endpoint, q url.Values, body interface{}, h http.Header) (*http.Response, error) {
+	return a.do(ctx, http.MethodPost, ep, q, body, h)
+}
+
+func (a *api) patch(ctx context.Context, ep endpoint, q url.Values, body interface{}, h http.Header) (*http.Response, error) {
+	return a.do(ctx, http.MethodPatch, ep, q, body, h)
+}
+
+func (a *api) put(ctx context.Context, ep endpoint, q url.Values, body interface{}, h http.Header) (*http.Response, error) {
+	return a.do(ctx, http.MethodPut, ep, q, body, h)
+}
+
+func (a *api) delete(ctx context.Context, ep endpoint, q url.Values, h http.Header) (*http.Response, error) {
+	return a.do(ctx, http.MethodDelete, ep, q, nil, h)
+}
+
+// request sends a request with JSON encoded body and decodes JSON
+// response into out. Both body and out may be nil.
+func (a *api) request(ctx context.Context, method string, ep endpoint, q url.Values, body, out interface{}) error {
+	resp, err := a.do(ctx, method, ep, q, body, nil)
+	if err != nil {
+		return err
 	}
 
-	if a.token.Expired() {
-		if err := a.refreshToken(); err != nil {
-			return nil, err
-		}
+	return decodeResponse(resp, out)
+}
+
+// do sends an authorized request refreshing expired token beforehand.
+// Non-nil body is encoded as JSON.
+func (a *api) do(ctx context.Context, method string, ep endpoint, q url.Values, body interface{}, h http.Header) (*http.Response, error) {
+	token, err := a.validToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.doWithToken(ctx, token, method, ep, q, body, h)
+	if IsUnauthorized(err) {
+		resp, err = a.retryUnauthorized(ctx, token, err, method, ep, q, body, h)
+	}
+	if err != nil {
+		return nil, redactError(err, a.clientSecret, token.AccessToken(), token.RefreshToken())
+	}
+
+	return resp, nil
+}
+
+// retryUnauthorized handles 401 response to a request made with token,
+// which amoCRM may have revoked or consider expired because of clock
+// skew. The token is refreshed regardless of its expiry through the
+// single-flight path and the request is retried once. If that doesn't
+// help, UnauthorizedError tells the user must authorize again.
+func (a *api) retryUnauthorized(ctx context.Context, token Token, unauthorized error, method string, ep endpoint, q url.Values, body interface{}, h http.Header) (*http.Response, error) {
+	if token.RefreshToken() == "" {
+		return nil, &UnauthorizedError{Err: unauthorized}
 	}
 
-	header := a.header()
+	fresh, err := a.refreshToken(ctx, token)
+	if err != nil {
+		return nil, reauthError(unauthorized, err)
+	}
+
+	resp, err := a.doWithToken(ctx, fresh, method, ep, q, body, h)
+	if IsUnauthorized(err) {
+		err = &UnauthorizedError{Err: err}
+	}
+	if err != nil {
+		return nil, redactError(err, fresh.AccessToken(), fresh.RefreshToken())
+	}
+
+	return resp, nil
+}
+
+// reauthError returns UnauthorizedError for refreshErr if the token
+// endpoint rejected the grant with 400 or 401 status code. Rate
+// limiting, server and network failures and cancellation don't mean
+// the grant is gone, so they are returned unchanged.
+func reauthError(unauthorized, refreshErr error) error {
+	var apiErr *APIError
+	if !errors.As(refreshErr, &apiErr) {
+		return refreshErr
+	}
+	switch apiErr.StatusCode {
+	case http.StatusBadRequest, http.StatusUnauthorized:
+		return &UnauthorizedError{Err: unauthorized, RefreshErr: refreshErr}
+	default:
+		return refreshErr
+	}
+}
+
+func (a *api) doWithToken(ctx context.Context, token Token, method string, ep endpoint, q url.Values, body interface{}, h http.Header) (*http.Response, error) {
+	header := a.header(token)
 	for k, v := range h {
 		if _, reserved := header[k]; !reserved {
 			header[k] = v
 		}
 	}
 
-	apiURL, err := a.url(ep.path(), q)
+	path := ep.path()
+	apiURL, err := a.url(path, q)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload []byte
+	if body != nil {
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("encode json request: %w", err)
+		}
+		header["Content-Type"] = []string{"application/json"}
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, sErr := a.send(ctx, &Request{
+			Endpoint: path,
+			Method:   method,
+			Attempt:  attempt,
+		}, apiURL, header, payload)
+		if sErr == nil {
+			return resp, nil
+		}
+
+		if attempt >= a.retry.MaxAttempts || !a.retry.retryable(method, sErr) {
+			return nil, retryErr(attempt, sErr)
+		}
+
+		delay := a.retry.backoff(attempt, sErr)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return nil, retryErr(attempt, sErr)
+		}
+
+		a.log(ctx, Event{
+			Kind:     EventRetry,
+			Method:   method,
+			Endpoint: path,
+			Attempt:  attempt,
+			Delay:    delay,
+			Err:      redactError(sErr, a.clientSecret, token.AccessToken(), token.RefreshToken()),
+		})
+
+		if a.retry.OnRetry != nil {
+			a.retry.OnRetry(attempt, sErr, delay)
+		}
+
+		if err = sleep(ctx, delay); err != nil {
+			return nil, retryErr(attempt, err)
+		}
+	}
+}
+
+// send makes a single attempt to send a request through middlewares
+// waiting for rate limiter beforehand. Non-2xx responses are returned
+// as APIError.
+func (a *api) send(ctx context.Context, req *Request, u *url.URL, header http.Header, payload []byte) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, u.String(), body)
 	if err != nil {
 		return nil, err
 	}
+	httpReq.Header = header.Clone()
+	req.HTTP = httpReq
+
+	waitStart := time.Now()
+	if err = a.wait(ctx); err != nil {
+		return nil, err
+	}
+	if a.metrics != nil && a.limiter != nil {
+		a.metrics.ObserveLimiterWait(a.currentDomain(), time.Since(waitStart))
+	}
 
-	return a.http.Do(&http.Request{
-		Method: http.MethodGet,
-		Header: header,
-		URL:    apiURL,
+	a.log(ctx, Event{
+		Kind:     EventRequest,
+		Method:   req.Method,
+		Endpoint: req.Endpoint,
+		Attempt:  req.Attempt,
+		Header:   redactHeader(httpReq.Header),
 	})
+
+	start := time.Now()
+	resp, err := a.handler(req)
+
+	e := Event{
+		Kind:     EventResponse,
+		Method:   req.Method,
+		Endpoint: req.Endpoint,
+		Attempt:  req.Attempt,
+		Duration: time.Since(start),
+		Err:      redactError(err, a.clientSecret, bearerToken(header)),
+	}
+	var apiErr *APIError
+	switch {
+	case resp != nil:
+		e.Status = resp.StatusCode
+	case errors.As(err, &apiErr):
+		e.Status = apiErr.StatusCode
+	}
+	a.log(ctx, e)
+
+	if a.metrics != nil {
+		a.metrics.ObserveRequest(a.currentDomain(), req.Method, req.Endpoint, e.Status, e.Duration, req.Attempt-1)
+	}
+
+	return resp, err
+}
+
+// bearerToken returns access token from authorization header, if any.
+func bearerToken(header http.Header) string {
+	auth := header.Get("Authorization")
+	if i := strings.IndexByte(auth, ' '); i >= 0 {
+		return auth[i+1:]
+	}
+	return ""
+}
+
+// retryErr wraps err into RetryError if the request was retried.
+func retryErr(attempts int, err error) error {
+	if attempts > 1 {
+		return &RetryError{Attempts: attempts, Err: err}
+	}
+	return err
+}
+
+// decodeResponse decodes JSON response body into out and closes it.
+// Empty body and 204 No Content leave out untouched.
+func decodeResponse(resp *http.Response, out interface{}) (err error) {
+	defer func() {
+		if clErr := resp.Body.Close(); clErr != nil {
+			if err != nil {
+				err = fmt.Errorf("close response body: %v: %v", clErr, err)
+			} else {
+				err = fmt.Errorf("close response body: %w", clErr)
+			}
+		}
+	}()
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	if dErr := json.NewDecoder(resp.Body).Decode(out); dErr != nil && !errors.Is(dErr, io.EOF) {
+		return fmt.Errorf("decode json response: %w", dErr)
+	}
+
+	return nil
 }
 
 func (a *api) setToken(token Token) error {
 	if token == nil {
 		return errors.New("invalid token")
 	}
+
+	a.mu.Lock()
 	a.token = token
+	a.mu.Unlock()
+
 	return nil
 }
 
 func (a *api) setDomain(domain string) error {
-	if !isValidDomain(domain) {
+	if _, ok := a.region(domain); !ok {
 		return errors.New("invalid domain")
 	}
 
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.fixedDomain && a.domain != domain {
+		return errors.New("domain is fixed by pool")
+	}
 	a.domain = domain
+
 	return nil
 }
 
+func (a *api) currentDomain() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.domain
+}
+
+func (a *api) currentToken() Token {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.token
+}
+
+// validToken returns current token refreshing it if expired.
+// Missing token is loaded from token store, if any.
+func (a *api) validToken(ctx context.Context) (Token, error) {
+	token := a.currentToken()
+	if token == nil {
+		var err error
+		if token, err = a.loadToken(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if token.Expired() {
+		fresh, err := a.refreshToken(ctx, token)
+		if err != nil {
+			return nil, reauthError(nil, err)
+		}
+		return fresh, nil
+	}
+
+	return token, nil
+}
+
 func (a *api) authorizationURL(state, mode string) (*url.URL, error) {
 	if state == "" {
 		return nil, oauth2Err("empty state")
@@ -155,13 +475,32 @@ func (a *api) authorizationURL(state, mode string) (*url.URL, error) {
 		"client_id": []string{a.clientID},
 	}.Encode()
 
-	authURL := "https://www.amocrm.ru/oauth?" + query
+	authURL := a.authURL
+	if authURL == "" {
+		authURL = a.regions[0].AuthorizeURL
+	}
 
-	return url.Parse(authURL)
+	return url.Parse(authURL + "?" + query)
 }
 
-func (a *api) getToken(grant GrantType, options url.Values, header http.Header) (Token, error) {
-	if !isValidDomain(a.domain) {
+// getToken exchanges grant for a new token. Client secret, code and
+// tokens are redacted from the returned error message.
+func (a *api) getToken(ctx context.Context, grant GrantType, options url.Values, header http.Header) (Token, error) {
+	token, err := a.fetchToken(ctx, grant, options, header)
+	if err != nil {
+		secrets := []string{a.clientSecret}
+		for _, key := range grant.fields {
+			secrets = append(secrets, options[key]...)
+		}
+		return nil, redactError(err, secrets...)
+	}
+
+	return token, nil
+}
+
+func (a *api) fetchToken(ctx context.Context, grant GrantType, options url.Values, header http.Header) (Token, error) {
+	region, ok := a.region(a.currentDomain())
+	if !ok {
 		return nil, oauth2Err("invalid accounts domain")
 	}
 
@@ -188,7 +527,10 @@ func (a *api) getToken(grant GrantType, options url.Values, header http.Header)
 	}
 
 	// Set request URL
-	tokenURL, err := a.url("/oauth2/access_token", nil)
+	tokenURL, err := a.url(tokenPath, nil)
+	if a.baseURL == "" && region.TokenHost != "" {
+		tokenURL, err = url.Parse(strings.TrimSuffix(region.TokenHost, "/") + tokenPath)
+	}
 	if err != nil {
 		return nil, oauth2Err("build request url")
 	}
@@ -202,20 +544,18 @@ func (a *api) getToken(grant GrantType, options url.Values, header http.Header)
 		}
 	}
 
-	// Create request body
-	reqBody := ioutil.NopCloser(strings.NewReader(data.Encode()))
-
-	// Build request
-	req := &http.Request{
-		Method: http.MethodPost,
-		Header: reqHeader,
-		URL:    tokenURL,
-		Body:   reqBody,
-	}
-
-	resp, err := a.http.Do(req)
+	// Send request
+	resp, err := a.send(ctx, &Request{
+		Endpoint: tokenPath,
+		Method:   http.MethodPost,
+		Attempt:  1,
+	}, tokenURL, reqHeader, []byte(data.Encode()))
 	if err != nil {
-		return nil, oauth2Err("send request")
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			return nil, oauth2Err("fetch token: %w", err)
+		}
+		return nil, oauth2Err("send request: %w", err)
 	}
 
 	respBody, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
@@ -226,10 +566,6 @@ func (a *api) getToken(grant GrantType, options url.Values, header http.Header)
 		return nil, oauth2Err("fetch response body")
 	}
 
-	if statusCode := resp.StatusCode; statusCode < 200 || statusCode > 299 {
-		return nil, oauth2Err("fetch token: response: %v - %s, request: %+v", resp.Status, respBody, req)
-	}
-
 	var jsonToken tokenJSON
 	if err = json.Unmarshal(respBody, &jsonToken); err != nil {
 		return nil, oauth2Err("parse token from json")
@@ -249,35 +585,187 @@ func (a *api) getToken(grant GrantType, options url.Values, header http.Header)
 	return token, nil
 }
 
-func (a *api) refreshToken() error {
-	if a.token.RefreshToken() == "" {
-		return oauth2Err("empty refresh token")
+// refreshCall is an in-flight or completed token refresh.
+type refreshCall struct {
+	done  chan struct{}
+	token Token
+	err   error
+}
+
+// refreshToken exchanges refresh token of the stale token for a new
+// pair. Concurrent calls are coalesced: exactly one request is sent
+// while other callers wait for its result. If the token has already
+// been replaced since stale was read, the current one is returned.
+//
+// The exchange runs detached from the context of any caller, because
+// amoCRM rotates the pair as soon as it handles the request: abandoned
+// halfway, the new pair would be lost along with the old refresh token.
+// Every caller waits for the result until its own ctx is done, while
+// the exchange always completes and the new pair is stored and saved.
+func (a *api) refreshToken(ctx context.Context, stale Token) (Token, error) {
+	a.mu.Lock()
+	if a.token != nil && a.token.AccessToken() != stale.AccessToken() && !a.token.Expired() {
+		token := a.token
+		a.mu.Unlock()
+		return token, nil
+	}
+
+	call := a.refresh
+	if call == nil {
+		call = &refreshCall{done: make(chan struct{})}
+		a.refresh = call
+		go a.runRefresh(detach(ctx), call, stale)
+	}
+	a.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.token, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// runRefresh performs the refresh of call, bounded by refreshTimeout.
+func (a *api) runRefresh(ctx context.Context, call *refreshCall, stale Token) {
+	ctx, cancel := context.WithTimeout(ctx, refreshTimeout)
+	defer cancel()
+
+	start := time.Now()
+	call.token, call.err = a.exchangeRefreshToken(ctx, stale)
+	a.log(ctx, Event{
+		Kind:     EventRefresh,
+		Method:   http.MethodPost,
+		Endpoint: tokenPath,
+		Duration: time.Since(start),
+		Err:      call.err,
+	})
+	if a.metrics != nil {
+		a.metrics.ObserveTokenRefresh(a.currentDomain(), call.err)
+	}
+	if call.err == nil {
+		// Refresh tokens are rotated, so the new pair must be saved
+		// even if the old one has already been invalidated.
+		if err := a.saveToken(ctx, call.token); err != nil {
+			call.err = err
+		}
 	}
 
-	token, err := a.getToken(refreshTokenGrant, url.Values{
+	a.mu.Lock()
+	if call.token != nil {
+		a.token = call.token
+	}
+	a.refresh = nil
+	a.mu.Unlock()
+	close(call.done)
+}
+
+// detachedContext carries values of its parent, but is never
+// cancelled and has no deadline, like context.WithoutCancel.
+type detachedContext struct {
+	parent context.Context
+}
+
+// detach returns a context with values of ctx, but without
+// its cancellation and deadline.
+func detach(ctx context.Context) context.Context {
+	return detachedContext{parent: ctx}
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
+
+func (a *api) exchangeRefreshToken(ctx context.Context, stale Token) (Token, error) {
+	if stale.RefreshToken() == "" {
+		return nil, oauth2Err("empty refresh token")
+	}
+
+	return a.getToken(ctx, refreshTokenGrant, url.Values{
 		"grant_type":    []string{"refresh_token"},
-		"refresh_token": []string{a.token.RefreshToken()},
+		"refresh_token": []string{stale.RefreshToken()},
 	}, nil)
+}
+
+// loadToken loads token of current domain from token store.
+func (a *api) loadToken(ctx context.Context) (Token, error) {
+	if a.store == nil {
+		return nil, errors.New("invalid token")
+	}
+
+	token, err := a.store.Load(ctx, a.currentDomain())
+	if errors.Is(err, ErrTokenNotFound) {
+		return nil, errors.New("invalid token")
+	}
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("load token: %w", err)
 	}
 
-	a.token = token
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// Token set concurrently wins over the stored one.
+	if a.token == nil {
+		a.token = token
+	}
+	return a.token, nil
+}
+
+// saveToken persists the new token of current domain
+// and notifies refresh callback, if any.
+func (a *api) saveToken(ctx context.Context, token Token) error {
+	domain := a.currentDomain()
+
+	if a.onRefresh != nil {
+		a.onRefresh(domain, token)
+	}
+
+	if a.store == nil {
+		return nil
+	}
+
+	if err := a.store.Save(ctx, domain, token); err != nil {
+		return fmt.Errorf("save token: %w", err)
+	}
 	return nil
 }
 
+// wait blocks until rate limiter allows a request to current domain.
+func (a *api) wait(ctx context.Context) error {
+	if a.limiter == nil {
+		return nil
+	}
+	return a.limiter.Wait(ctx, a.currentDomain())
+}
+
 func (a *api) url(path string, q url.Values) (*url.URL, error) {
-	if !isValidDomain(a.domain) {
+	domain := a.currentDomain()
+	if _, ok := a.region(domain); !ok {
 		return nil, oauth2Err("invalid accounts domain")
 	}
 
-	endpointURL := "https://" + a.domain + path + "?" + q.Encode()
+	base := "https://" + domain
+	if a.baseURL != "" {
+		base = strings.TrimSuffix(a.baseURL, "/")
+	}
 
-	return url.Parse(endpointURL)
+	return url.Parse(base + path + "?" + q.Encode())
 }
 
-func (a *api) header() http.Header {
-	authHeader := a.token.TokenType() + " " + a.token.AccessToken()
+func (a *api) header(token Token) http.Header {
+	authHeader := token.TokenType() + " " + token.AccessToken()
 
 	header := a.baseHeader()
 	header["Authorization"] = []string{authHeader}
@@ -287,28 +775,10 @@ func (a *api) header() http.Header {
 
 func (a *api) baseHeader() http.Header {
 	return http.Header{
-		"User-Agent": []string{userAgent},
+		"User-Agent": []string{a.userAgent},
 	}
 }
 
-func isValidDomain(domain string) bool {
-	if domain == "" {
-		return false
-	}
-
-	parts := strings.Split(domain, ".")
-	if len(parts) != 3 ||
-		parts[0] == "" ||
-		parts[0] == "www" ||
-		len(parts[0]) > 63 ||
-		parts[1] != "amocrm" ||
-		parts[2] != "ru" && parts[2] != "com" {
-		return false
-	}
-
-	return true
-}
-
 func oauth2Err(format string, args ...interface{}) error {
 	return fmt.Errorf("oauth2: "+format, args...)
 }
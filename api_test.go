@@ -0,0 +1,126 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAPI(t *testing.T, handler http.HandlerFunc) *api {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	a := newAPI("client_id", "client_secret", "redirect_url", WithBaseURL(srv.URL))
+	require.NoError(t, a.setDomain("example.amocrm.ru"))
+	require.NoError(t, a.setToken(NewToken("access_token", "refresh_token", "bearer", time.Time{})))
+
+	return a
+}
+
+func TestAPI_Request(t *testing.T) {
+	type item struct {
+		Name string `json:"name"`
+	}
+
+	type sendFunc func(a *api, body interface{}) (*http.Response, error)
+
+	cases := map[string]sendFunc{
+		http.MethodPost: func(a *api, body interface{}) (*http.Response, error) {
+			return a.post(context.Background(), "leads", nil, body, nil)
+		},
+		http.MethodPatch: func(a *api, body interface{}) (*http.Response, error) {
+			return a.patch(context.Background(), "leads", nil, body, nil)
+		},
+		http.MethodPut: func(a *api, body interface{}) (*http.Response, error) {
+			return a.put(context.Background(), "leads", nil, body, nil)
+		},
+	}
+
+	for method, send := range cases {
+		method := method
+		a := newTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, method, r.Method)
+			require.Equal(t, "/api/v4/leads", r.URL.Path)
+			require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			require.Equal(t, "Bearer access_token", r.Header.Get("Authorization"))
+
+			body, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			require.JSONEq(t, `[{"name":"lead"}]`, string(body))
+
+			_, _ = w.Write([]byte(`[{"name":"created"}]`))
+		})
+
+		resp, err := send(a, []item{{Name: "lead"}})
+		require.NoError(t, err)
+
+		var got []item
+		require.NoError(t, decodeResponse(resp, &got))
+		require.Equal(t, []item{{Name: "created"}}, got)
+	}
+}
+
+func TestAPI_Request_NoContent(t *testing.T) {
+	a := newTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodDelete, r.Method)
+		require.Empty(t, r.Header.Get("Content-Type"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	resp, err := a.delete(context.Background(), "leads/1", nil, nil)
+	require.NoError(t, err)
+
+	var got map[string]interface{}
+	require.NoError(t, decodeResponse(resp, &got))
+	require.Nil(t, got)
+}
+
+func TestAPI_Request_Decode(t *testing.T) {
+	a := newTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		_, _ = w.Write([]byte(`{"id":7}`))
+	})
+
+	var got struct {
+		ID int `json:"id"`
+	}
+	require.NoError(t, a.request(context.Background(), http.MethodGet, "leads/7", nil, nil, &got))
+	require.Equal(t, 7, got.ID)
+}
+
+func TestAPI_Request_EncodeError(t *testing.T) {
+	a := newTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("request must not be sent")
+	})
+
+	err := a.request(context.Background(), http.MethodPost, "leads", nil, make(chan int), nil)
+	var jsonErr *json.UnsupportedTypeError
+	require.True(t, errors.As(err, &jsonErr))
+}
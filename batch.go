@@ -0,0 +1,157 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Maximum number of entities amoCRM accepts in a single
+// create or update request of the endpoint.
+const (
+	leadsBatchLimit    = 250
+	contactsBatchLimit = 250
+)
+
+// BatchItem is the outcome of a batch operation for a single entity.
+// ID is the ID of created or updated entity, Err is non-nil if amoCRM
+// rejected the entity or the request containing it.
+type BatchItem struct {
+	ID  int
+	Err error
+}
+
+// BatchResult holds outcomes of a batch operation,
+// one per entity in the order they were given.
+type BatchResult []BatchItem
+
+// Failed returns indexes of entities the operation failed for.
+func (r BatchResult) Failed() []int {
+	var failed []int
+	for i, item := range r {
+		if item.Err != nil {
+			failed = append(failed, i)
+		}
+	}
+	return failed
+}
+
+// batchResponse is the response of create and update endpoints.
+type batchResponse struct {
+	Embedded map[string][]struct {
+		ID        int    `json:"id"`
+		RequestID string `json:"request_id"`
+	} `json:"_embedded"`
+}
+
+// batch sends n entities to ep in chunks of at most size entities,
+// one chunk at a time so every request goes through rate limiter.
+// Chunk returns request body for entities [from, to), every entity
+// having its index as request_id. Entities of a rejected chunk are
+// all failed, while validation errors go to entities they refer to.
+func (a *api) batch(ctx context.Context, method string, ep endpoint, entity string, n, size int,
+	chunk func(from, to int) interface{}) (BatchResult, error) {
+	result := make(BatchResult, n)
+
+	for from := 0; from < n; from += size {
+		to := from + size
+		if to > n {
+			to = n
+		}
+
+		if err := ctx.Err(); err != nil {
+			result.fail(from, n, err)
+			break
+		}
+
+		var resp batchResponse
+		if err := a.request(ctx, method, ep, nil, chunk(from, to), &resp); err != nil {
+			result.fail(from, to, err)
+			continue
+		}
+
+		for i, item := range resp.Embedded[entity] {
+			idx, err := strconv.Atoi(item.RequestID)
+			if err != nil || idx < from || idx >= to {
+				// Fall back to the order of entities in the chunk.
+				idx = from + i
+			}
+			if idx < to {
+				result[idx].ID = item.ID
+			}
+		}
+		for i := from; i < to; i++ {
+			if result[i].ID == 0 {
+				result[i].Err = fmt.Errorf("%s: item %d missing in response", entity, i)
+			}
+		}
+	}
+
+	if failed := result.Failed(); len(failed) > 0 {
+		return result, fmt.Errorf("%d of %d %s failed: %w", len(failed), n, entity, firstErr(result, failed))
+	}
+	return result, nil
+}
+
+// fail sets err to entities [from, to). Validation errors are narrowed
+// down to the entities they refer to by request_id, so the entities
+// rejected only because of the others get no validation errors.
+func (r BatchResult) fail(from, to int, err error) {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || len(apiErr.ValidationErrors) == 0 {
+		for i := from; i < to; i++ {
+			r[i].Err = err
+		}
+		return
+	}
+
+	chunkErr := *apiErr
+	chunkErr.ValidationErrors = nil
+	for i := from; i < to; i++ {
+		r[i].Err = &chunkErr
+	}
+
+	for _, ve := range apiErr.ValidationErrors {
+		idx, convErr := strconv.Atoi(ve.RequestID)
+		if convErr != nil || idx < from || idx >= to {
+			continue
+		}
+
+		itemErr := *apiErr
+		itemErr.ValidationErrors = []ValidationError{ve}
+		r[idx].Err = &itemErr
+	}
+}
+
+// firstErr returns the first error having validation errors,
+// as it explains the failure best, or the first error otherwise.
+func firstErr(result BatchResult, failed []int) error {
+	for _, i := range failed {
+		var apiErr *APIError
+		if errors.As(result[i].Err, &apiErr) && len(apiErr.ValidationErrors) > 0 {
+			return result[i].Err
+		}
+	}
+	return result[failed[0]].Err
+}
@@ -21,18 +21,27 @@
 package amocrm
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"fmt"
+	"net/http"
 	"net/url"
+	"strings"
 )
 
 // Provider is a wrapper for authorization and making requests.
 type Client interface {
 	AuthorizeURL(state, mode string) (*url.URL, error)
 	TokenByCode(code string) (Token, error)
+	TokenByCodeContext(ctx context.Context, code string) (Token, error)
 	SetToken(token Token) error
 	SetDomain(domain string) error
 	Accounts() Accounts
+	Leads() Leads
+	Contacts() Contacts
+	Do(method, path string, query url.Values, body, out interface{}) error
+	DoContext(ctx context.Context, method, path string, query url.Values, body, out interface{}) error
 }
 
 // Verify interface compliance.
@@ -56,30 +65,16 @@ func RandomState() string {
 }
 
 // New allocates and returns a new amoCRM API Client.
-func New(clientID, clientSecret, redirectURL string) Client {
+// Use options to override transport, hosts and request metadata.
+func New(clientID, clientSecret, redirectURL string, opts ...Option) Client {
 	return &amoCRM{
-		api: newAPI(clientID, clientSecret, redirectURL),
+		api: newAPI(clientID, clientSecret, redirectURL, opts...),
 	}
 }
 
 // AuthorizeURL returns a URL of page to ask for permissions.
 func (a *amoCRM) AuthorizeURL(state, mode string) (*url.URL, error) {
-	if state == "" {
-		return nil, oauth2Err("empty state")
-	}
-	if mode != PostMessageMode && mode != PopupMode {
-		return nil, oauth2Err("unexpected mode")
-	}
-
-	query := url.Values{
-		"mode":      []string{mode},
-		"state":     []string{state},
-		"client_id": []string{a.api.clientID},
-	}.Encode()
-
-	authURL := "https://www.amocrm.ru/oauth?" + query
-
-	return url.Parse(authURL)
+	return a.api.authorizationURL(state, mode)
 }
 
 // SetToken stores given token to sign API requests.
@@ -95,13 +90,94 @@ func (a *amoCRM) SetDomain(domain string) error {
 // TokenByCode makes a handshake with amoCRM, exchanging given
 // authorization code for a set of tokens.
 func (a *amoCRM) TokenByCode(code string) (Token, error) {
-	return a.api.getToken(authorizationCodeGrant, url.Values{
+	return a.TokenByCodeContext(context.Background(), code)
+}
+
+// TokenByCodeContext is like TokenByCode but uses ctx to
+// control the lifetime of the handshake request.
+// Received token is saved to token store, if any.
+func (a *amoCRM) TokenByCodeContext(ctx context.Context, code string) (Token, error) {
+	token, err := a.api.getToken(ctx, authorizationCodeGrant, url.Values{
 		"code":       []string{code},
 		"grant_type": []string{"authorization_code"},
 	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = a.api.saveToken(ctx, token); err != nil {
+		return token, err
+	}
+
+	return token, nil
 }
 
 // Accounts returns accounts repository.
 func (a *amoCRM) Accounts() Accounts {
 	return newAccounts(a.api)
 }
+
+// Leads returns leads repository.
+func (a *amoCRM) Leads() Leads {
+	return newLeads(a.api)
+}
+
+// Contacts returns contacts repository.
+func (a *amoCRM) Contacts() Contacts {
+	return newContacts(a.api)
+}
+
+// Do sends a request to an endpoint the package doesn't cover yet, with
+// the same authorization, token refresh, rate limiting and retries as
+// other methods. Path is relative to the API root, e.g. "leads/pipelines",
+// or absolute, e.g. "/api/v4/leads/pipelines". Non-nil body is encoded
+// as JSON, pass json.RawMessage to send prepared JSON. The response is
+// decoded as JSON into out, unless out is nil or there is no content.
+func (a *amoCRM) Do(method, path string, query url.Values, body, out interface{}) error {
+	return a.DoContext(context.Background(), method, path, query, body, out)
+}
+
+// DoContext is like Do but uses ctx to control the lifetime
+// of the request, including implicit token refresh.
+func (a *amoCRM) DoContext(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	method, err := checkMethod(method)
+	if err != nil {
+		return err
+	}
+	if err := checkPath(path); err != nil {
+		return err
+	}
+
+	if err := a.api.request(ctx, method, endpoint(path), query, body, out); err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+// checkMethod returns method in canonical upper case,
+// rejecting methods amoCRM API doesn't know.
+func checkMethod(method string) (string, error) {
+	switch m := strings.ToUpper(method); m {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return m, nil
+	case "":
+		return "", fmt.Errorf("empty request method")
+	default:
+		return "", fmt.Errorf("unknown request method: %s", method)
+	}
+}
+
+// checkPath rejects paths that would escape the account domain
+// or carry query parameters, which go to the query argument.
+func checkPath(path string) error {
+	switch {
+	case strings.TrimPrefix(path, "/") == "":
+		return fmt.Errorf("empty request path")
+	case strings.Contains(path, "://") || strings.HasPrefix(path, "//"):
+		return fmt.Errorf("request path must not contain host: %s", path)
+	case strings.ContainsAny(path, "?#"):
+		return fmt.Errorf("request path must not contain query: %s", path)
+	}
+	return nil
+}
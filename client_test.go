@@ -23,8 +23,16 @@
 package amocrm_test
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -55,6 +63,16 @@ func TestAmoCRM_Accounts(t *testing.T) {
 	require.Implements(t, (*amocrm.Accounts)(nil), cl.Accounts())
 }
 
+func TestAmoCRM_Leads(t *testing.T) {
+	cl := amocrm.New(clientID, clientSecret, redirectURL)
+	require.Implements(t, (*amocrm.Leads)(nil), cl.Leads())
+}
+
+func TestAmoCRM_Contacts(t *testing.T) {
+	cl := amocrm.New(clientID, clientSecret, redirectURL)
+	require.Implements(t, (*amocrm.Contacts)(nil), cl.Contacts())
+}
+
 func TestAmoCRM_AuthorizeURL(t *testing.T) {
 	cases := []struct {
 		state string
@@ -100,6 +118,8 @@ func TestAmoCRM_SetDomain(t *testing.T) {
 		{domain: "any.amocrm.", isValid: false},
 		{domain: "any.amocrm.ru", isValid: true},
 		{domain: "any.amocrm.com", isValid: true},
+		{domain: "any.kommo.com", isValid: true},
+		{domain: "www.kommo.com", isValid: false},
 	}
 
 	cl := amocrm.New(clientID, clientSecret, redirectURL)
@@ -112,3 +132,373 @@ func TestAmoCRM_SetDomain(t *testing.T) {
 		}
 	}
 }
+
+func TestAmoCRM_TokenByCodeContext(t *testing.T) {
+	cl := amocrm.New(clientID, clientSecret, redirectURL)
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	token, err := cl.TokenByCodeContext(ctx, "code")
+	require.Nil(t, token)
+	require.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestAmoCRM_ConcurrentRefresh(t *testing.T) {
+	var refreshes int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth2/access_token" {
+			n := atomic.AddInt32(&refreshes, 1)
+			require.NoError(t, r.ParseForm())
+			require.Equal(t, refreshToken, r.PostForm.Get("refresh_token"))
+
+			// Keep refresh in flight while other goroutines pile up.
+			time.Sleep(50 * time.Millisecond)
+			if n > 1 {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"title":"invalid_grant"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"access_token":"fresh","refresh_token":"next","token_type":"bearer","expires_in":86400}`))
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}))
+	defer srv.Close()
+
+	cl := amocrm.New(clientID, clientSecret, redirectURL, amocrm.WithBaseURL(srv.URL), amocrm.WithLimiter(nil))
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+	require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Now())))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cl.Accounts().Current(amocrm.AccountsConfig{})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&refreshes))
+}
+
+func TestAmoCRM_RefreshOutlivesCaller(t *testing.T) {
+	var refreshes int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth2/access_token" {
+			atomic.AddInt32(&refreshes, 1)
+			// amoCRM rotates the pair as soon as it gets the request.
+			time.Sleep(100 * time.Millisecond)
+			_, _ = w.Write([]byte(`{"access_token":"fresh","refresh_token":"next","token_type":"bearer","expires_in":86400}`))
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}))
+	defer srv.Close()
+
+	type ctxKey struct{}
+	refreshed := make(chan interface{}, 1)
+	logger := amocrm.LoggerFunc(func(ctx context.Context, e amocrm.Event) {
+		if e.Kind == amocrm.EventRefresh {
+			refreshed <- ctx.Value(ctxKey{})
+		}
+	})
+
+	store := amocrm.NewMemoryTokenStore()
+	cl := amocrm.New(clientID, clientSecret, redirectURL, amocrm.WithBaseURL(srv.URL), amocrm.WithLimiter(nil),
+		amocrm.WithTokenStore(store), amocrm.WithLogger(logger))
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+	require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Now())))
+
+	// The caller starting the refresh gives up halfway through it.
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), ctxKey{}, "value"), 30*time.Millisecond)
+	defer cancel()
+
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := cl.Accounts().CurrentContext(ctx, amocrm.AccountsConfig{})
+		leaderErr <- err
+	}()
+
+	// Another caller joins the refresh in flight and gets its result.
+	time.Sleep(10 * time.Millisecond)
+	_, err := cl.Accounts().Current(amocrm.AccountsConfig{})
+	require.NoError(t, err)
+
+	require.True(t, errors.Is(<-leaderErr, context.DeadlineExceeded))
+	require.Equal(t, "value", <-refreshed)
+
+	saved, err := store.Load(context.Background(), "example.amocrm.ru")
+	require.NoError(t, err)
+	require.Equal(t, "fresh", saved.AccessToken())
+	require.Equal(t, "next", saved.RefreshToken())
+
+	_, err = cl.Accounts().Current(amocrm.AccountsConfig{})
+	require.NoError(t, err)
+	require.Equal(t, int32(1), atomic.LoadInt32(&refreshes))
+}
+
+func TestAmoCRM_RefreshWithoutWaiters(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"access_token":"fresh","refresh_token":"next","token_type":"bearer","expires_in":86400}`))
+	}))
+	defer srv.Close()
+
+	saved := make(chan amocrm.Token, 1)
+	cl := amocrm.New(clientID, clientSecret, redirectURL, amocrm.WithBaseURL(srv.URL), amocrm.WithLimiter(nil),
+		amocrm.WithOnTokenRefresh(func(domain string, token amocrm.Token) {
+			saved <- token
+		}))
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+	require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Now())))
+
+	// The only caller is cancelled mid-refresh, the new pair is kept anyway.
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		cancel()
+	}()
+	_, err := cl.Accounts().CurrentContext(ctx, amocrm.AccountsConfig{})
+	require.True(t, errors.Is(err, context.Canceled))
+
+	select {
+	case token := <-saved:
+		require.Equal(t, "next", token.RefreshToken())
+	case <-time.After(time.Second):
+		t.Fatal("refreshed token was not saved")
+	}
+}
+
+func TestAmoCRM_RefreshOnUnauthorized(t *testing.T) {
+	var refreshes, calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth2/access_token" {
+			atomic.AddInt32(&refreshes, 1)
+			time.Sleep(50 * time.Millisecond)
+			_, _ = w.Write([]byte(`{"access_token":"fresh","refresh_token":"next","token_type":"bearer","expires_in":86400}`))
+			return
+		}
+
+		atomic.AddInt32(&calls, 1)
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}))
+	defer srv.Close()
+
+	// The token is revoked, though it isn't expired yet.
+	cl := amocrm.New(clientID, clientSecret, redirectURL, amocrm.WithBaseURL(srv.URL), amocrm.WithLimiter(nil))
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+	require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Now().Add(time.Hour))))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cl.Accounts().Current(amocrm.AccountsConfig{})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&refreshes))
+	require.Equal(t, int32(20), atomic.LoadInt32(&calls))
+}
+
+func TestAmoCRM_ReauthRequired(t *testing.T) {
+	var refreshes, calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth2/access_token" {
+			atomic.AddInt32(&refreshes, 1)
+			_, _ = w.Write([]byte(`{"access_token":"fresh","refresh_token":"next","token_type":"bearer","expires_in":86400}`))
+			return
+		}
+
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	cl := amocrm.New(clientID, clientSecret, redirectURL, amocrm.WithBaseURL(srv.URL), amocrm.WithLimiter(nil))
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+	require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Time{})))
+
+	// The request is retried only once with the refreshed token.
+	_, err := cl.Accounts().Current(amocrm.AccountsConfig{})
+	var authErr *amocrm.UnauthorizedError
+	require.True(t, errors.As(err, &authErr))
+	require.Nil(t, authErr.RefreshErr)
+	require.True(t, amocrm.IsUnauthorized(err))
+	require.Equal(t, int32(1), atomic.LoadInt32(&refreshes))
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	require.NotContains(t, err.Error(), "fresh")
+
+	// Token without refresh token can't be refreshed at all.
+	require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, "", tokenType, time.Time{})))
+	_, err = cl.Accounts().Current(amocrm.AccountsConfig{})
+	require.True(t, amocrm.IsReauthRequired(err))
+	require.Equal(t, int32(1), atomic.LoadInt32(&refreshes))
+	require.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestAmoCRM_RefreshUnavailable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth2/access_token" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	cl := amocrm.New(clientID, clientSecret, redirectURL, amocrm.WithBaseURL(srv.URL), amocrm.WithLimiter(nil),
+		amocrm.WithRetryPolicy(amocrm.RetryPolicy{MaxAttempts: 1}))
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+	require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Time{})))
+
+	// Unavailable token endpoint doesn't mean the grant is gone.
+	_, err := cl.Accounts().Current(amocrm.AccountsConfig{})
+	require.Error(t, err)
+	require.False(t, amocrm.IsReauthRequired(err))
+	var apiErr *amocrm.APIError
+	require.True(t, errors.As(err, &apiErr))
+	require.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+}
+
+func TestAmoCRM_RefreshRejected(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth2/access_token" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"title":"Bad Request","hint":"invalid_grant"}`))
+			return
+		}
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer srv.Close()
+
+	cl := amocrm.New(clientID, clientSecret, redirectURL, amocrm.WithBaseURL(srv.URL), amocrm.WithLimiter(nil))
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+	require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Now().Add(-time.Hour))))
+
+	// Expired token with revoked grant is never sent.
+	_, err := cl.Accounts().Current(amocrm.AccountsConfig{})
+	var authErr *amocrm.UnauthorizedError
+	require.True(t, errors.As(err, &authErr))
+	require.Nil(t, authErr.Err)
+	require.Error(t, authErr.RefreshErr)
+	require.True(t, amocrm.IsUnauthorized(err))
+	require.Equal(t, int32(0), atomic.LoadInt32(&calls))
+	require.NotContains(t, err.Error(), refreshToken)
+}
+
+func TestAmoCRM_Do(t *testing.T) {
+	type pipeline struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+accessToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/v4/leads/pipelines":
+			if r.URL.Query().Get("with") != "statuses" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`{"_embedded":{"pipelines":[{"id":1,"name":"Sales"}]}}`))
+		case "POST /api/v2/notes":
+			body, _ := ioutil.ReadAll(r.Body)
+			if string(body) != `{"text":"hi"}` || r.Header.Get("Content-Type") != "application/json" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	cl := amocrm.New(clientID, clientSecret, redirectURL, amocrm.WithBaseURL(srv.URL), amocrm.WithLimiter(nil))
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+	require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Time{})))
+
+	var page struct {
+		Embedded struct {
+			Pipelines []pipeline `json:"pipelines"`
+		} `json:"_embedded"`
+	}
+	err := cl.Do(http.MethodGet, "leads/pipelines", url.Values{"with": {"statuses"}}, nil, &page)
+	require.NoError(t, err)
+	require.Equal(t, []pipeline{{ID: 1, Name: "Sales"}}, page.Embedded.Pipelines)
+
+	out := "untouched"
+	err = cl.DoContext(context.Background(), http.MethodPost, "/api/v2/notes", nil, json.RawMessage(`{"text":"hi"}`), &out)
+	require.NoError(t, err)
+	require.Equal(t, "untouched", out)
+
+	err = cl.Do(http.MethodGet, "missing", nil, nil, nil)
+	require.True(t, amocrm.IsNotFound(err))
+	require.Contains(t, err.Error(), "GET missing")
+
+	for _, path := range []string{"", "/", "https://evil.example.com/api", "//evil.example.com/api", "leads?page=2"} {
+		require.Error(t, cl.Do(http.MethodGet, path, nil, nil, nil), path)
+	}
+
+	for _, method := range []string{"", "FETCH", "get "} {
+		require.Error(t, cl.Do(method, "leads/pipelines", nil, nil, nil), method)
+	}
+}
+
+func TestAmoCRM_DoLowercaseMethod(t *testing.T) {
+	var methods []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		if len(methods) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}))
+	defer srv.Close()
+
+	cl := amocrm.New(clientID, clientSecret, redirectURL, amocrm.WithBaseURL(srv.URL), amocrm.WithLimiter(nil),
+		amocrm.WithRetryPolicy(amocrm.RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond}))
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+	require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Time{})))
+
+	require.NoError(t, cl.Do("get", "leads/pipelines/1", nil, nil, nil))
+	require.Equal(t, []string{http.MethodGet, http.MethodGet}, methods)
+}
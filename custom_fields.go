@@ -0,0 +1,377 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Custom field types.
+const (
+	FieldTypeText         = "text"
+	FieldTypeTextarea     = "textarea"
+	FieldTypeNumeric      = "numeric"
+	FieldTypeCheckbox     = "checkbox"
+	FieldTypeSelect       = "select"
+	FieldTypeMultiselect  = "multiselect"
+	FieldTypeRadiobutton  = "radiobutton"
+	FieldTypeMultitext    = "multitext"
+	FieldTypeDate         = "date"
+	FieldTypeDateTime     = "date_time"
+	FieldTypeBirthday     = "birthday"
+	FieldTypeURL          = "url"
+	FieldTypeSmartAddress = "smart_address"
+	FieldTypeLegalEntity  = "legal_entity"
+	FieldTypeTrackingData = "tracking_data"
+	FieldTypePrice        = "price"
+	FieldTypeLinkedEntity = "linked_entity"
+	FieldTypeChainedList  = "chained_list"
+)
+
+// Codes of predefined contact fields.
+const (
+	FieldCodePhone = "PHONE"
+	FieldCodeEmail = "EMAIL"
+)
+
+// Enum codes of phone and email values.
+const (
+	EnumWork    = "WORK"
+	EnumWorkDD  = "WORKDD"
+	EnumMobile  = "MOB"
+	EnumFax     = "FAX"
+	EnumHome    = "HOME"
+	EnumPrivate = "PRIV"
+	EnumOther   = "OTHER"
+)
+
+// CustomFields holds values of custom fields of an entity,
+// found in "custom_fields_values".
+type CustomFields []CustomField
+
+// CustomField holds values of a single custom field. Field is identified
+// by ID or, for predefined fields like phones, by code. Field without
+// values clears the field when the entity is sent to amoCRM.
+type CustomField struct {
+	FieldID   int          `json:"field_id,omitempty"`
+	FieldCode string       `json:"field_code,omitempty"`
+	FieldName string       `json:"field_name,omitempty"`
+	FieldType string       `json:"field_type,omitempty"`
+	Values    []FieldValue `json:"values"`
+}
+
+// MarshalJSON encodes empty values as null, which
+// is how amoCRM is asked to clear the field.
+func (f CustomField) MarshalJSON() ([]byte, error) {
+	type field CustomField
+	if len(f.Values) == 0 {
+		f.Values = nil
+	}
+	return json.Marshal(field(f))
+}
+
+// FieldValue is a single value of a custom field. The shape of Value
+// depends on the type of the field, use typed getters to read it and
+// constructors like TextValue or DateValue to build it.
+type FieldValue struct {
+	Value    json.RawMessage `json:"value,omitempty"`
+	EnumID   int             `json:"enum_id,omitempty"`
+	EnumCode string          `json:"enum_code,omitempty"`
+
+	// err is set by constructors for values that can't be sent.
+	err error
+}
+
+// MarshalJSON fails for values that can't be sent to amoCRM,
+// such as NumberValue of NaN.
+func (v FieldValue) MarshalJSON() ([]byte, error) {
+	if v.err != nil {
+		return nil, v.err
+	}
+	type value FieldValue
+	return json.Marshal(value(v))
+}
+
+// LegalEntity is a value of legal_entity field.
+type LegalEntity struct {
+	Name                      string `json:"name"`
+	EntityType                int    `json:"entity_type,omitempty"`
+	VatID                     string `json:"vat_id,omitempty"`
+	TaxRegistrationReasonCode string `json:"tax_registration_reason_code,omitempty"`
+	Address                   string `json:"address,omitempty"`
+	KPP                       string `json:"kpp,omitempty"`
+	ExternalUID               string `json:"external_uid,omitempty"`
+}
+
+// LinkedEntity is a value of linked_entity field.
+type LinkedEntity struct {
+	Name       string `json:"name,omitempty"`
+	EntityID   int    `json:"entity_id"`
+	EntityType string `json:"entity_type"`
+	CatalogID  int    `json:"catalog_id,omitempty"`
+}
+
+// ChainedList is a value of chained_list field.
+type ChainedList struct {
+	CatalogID        int `json:"catalog_id"`
+	CatalogElementID int `json:"catalog_element_id"`
+}
+
+// Field returns the field with id, or nil if there is none.
+func (cf CustomFields) Field(id int) *CustomField {
+	for i := range cf {
+		if cf[i].FieldID == id {
+			return &cf[i]
+		}
+	}
+	return nil
+}
+
+// FieldByCode returns the field with code, or nil if there is none.
+func (cf CustomFields) FieldByCode(code string) *CustomField {
+	for i := range cf {
+		if cf[i].FieldCode == code {
+			return &cf[i]
+		}
+	}
+	return nil
+}
+
+// Values returns values of the field with id, or nil if there is none.
+func (cf CustomFields) Values(id int) []FieldValue {
+	if f := cf.Field(id); f != nil {
+		return f.Values
+	}
+	return nil
+}
+
+// Phones returns values of the predefined phone field,
+// with EnumCode set to EnumWork, EnumMobile and so on.
+func (cf CustomFields) Phones() []FieldValue {
+	if f := cf.FieldByCode(FieldCodePhone); f != nil {
+		return f.Values
+	}
+	return nil
+}
+
+// Emails returns values of the predefined email field,
+// with EnumCode set to EnumWork, EnumPrivate or EnumOther.
+func (cf CustomFields) Emails() []FieldValue {
+	if f := cf.FieldByCode(FieldCodeEmail); f != nil {
+		return f.Values
+	}
+	return nil
+}
+
+// Set replaces values of the field with id, adding the field if needed.
+// Setting no values clears the field.
+func (cf *CustomFields) Set(id int, values ...FieldValue) {
+	if f := cf.Field(id); f != nil {
+		f.Values = values
+		return
+	}
+	*cf = append(*cf, CustomField{FieldID: id, Values: values})
+}
+
+// SetByCode is like Set but identifies the field by code.
+func (cf *CustomFields) SetByCode(code string, values ...FieldValue) {
+	if f := cf.FieldByCode(code); f != nil {
+		f.Values = values
+		return
+	}
+	*cf = append(*cf, CustomField{FieldCode: code, Values: values})
+}
+
+// SetPhones replaces values of the predefined phone field.
+func (cf *CustomFields) SetPhones(values ...FieldValue) {
+	cf.SetByCode(FieldCodePhone, values...)
+}
+
+// SetEmails replaces values of the predefined email field.
+func (cf *CustomFields) SetEmails(values ...FieldValue) {
+	cf.SetByCode(FieldCodeEmail, values...)
+}
+
+// Clear makes amoCRM remove all values of the field with id.
+func (cf *CustomFields) Clear(id int) {
+	cf.Set(id)
+}
+
+// TextValue returns value of text, textarea, url,
+// tracking_data and other string fields.
+func TextValue(s string) FieldValue {
+	return FieldValue{Value: mustMarshal(s)}
+}
+
+// NumberValue returns value of numeric and price fields. JSON has no
+// NaN or infinity, so such value fails to encode with an error.
+func NumberValue(f float64) FieldValue {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return FieldValue{err: fmt.Errorf("amocrm: number field value must be finite, got %v", f)}
+	}
+	return FieldValue{Value: mustMarshal(f)}
+}
+
+// BoolValue returns value of checkbox field.
+func BoolValue(b bool) FieldValue {
+	return FieldValue{Value: mustMarshal(b)}
+}
+
+// DateValue returns value of date, date_time and birthday fields.
+func DateValue(t time.Time) FieldValue {
+	return FieldValue{Value: mustMarshal(t.Unix())}
+}
+
+// EnumValue returns value of select, multiselect and radiobutton
+// fields, identified by enum ID.
+func EnumValue(enumID int) FieldValue {
+	return FieldValue{EnumID: enumID}
+}
+
+// MultitextValue returns value of multitext and smart_address
+// fields, e.g. MultitextValue("+79990000000", EnumMobile).
+func MultitextValue(value, enumCode string) FieldValue {
+	return FieldValue{Value: mustMarshal(value), EnumCode: enumCode}
+}
+
+// LegalEntityValue returns value of legal_entity field.
+func LegalEntityValue(e LegalEntity) FieldValue {
+	return FieldValue{Value: mustMarshal(e)}
+}
+
+// LinkedEntityValue returns value of linked_entity field.
+func LinkedEntityValue(e LinkedEntity) FieldValue {
+	return FieldValue{Value: mustMarshal(e)}
+}
+
+// ChainedListValue returns value of chained_list field.
+func ChainedListValue(c ChainedList) FieldValue {
+	return FieldValue{Value: mustMarshal(c)}
+}
+
+// String returns the value as text. Numbers and booleans are
+// formatted as in JSON, objects are returned as raw JSON.
+func (v FieldValue) String() string {
+	var s string
+	if err := json.Unmarshal(v.Value, &s); err == nil {
+		return s
+	}
+	if v.isNull() {
+		return ""
+	}
+	return string(v.Value)
+}
+
+// Float returns the value of numeric or price field,
+// sent by amoCRM either as a number or as a string.
+func (v FieldValue) Float() (float64, error) {
+	var f float64
+	if err := json.Unmarshal(v.Value, &f); err == nil {
+		return f, nil
+	}
+
+	f, err := strconv.ParseFloat(strings.TrimSpace(v.String()), 64)
+	if err != nil {
+		return 0, fmt.Errorf("decode number field value: %w", err)
+	}
+	return f, nil
+}
+
+// Bool returns the value of checkbox field.
+func (v FieldValue) Bool() (bool, error) {
+	var b bool
+	if err := json.Unmarshal(v.Value, &b); err == nil {
+		return b, nil
+	}
+
+	switch v.String() {
+	case "1", "true":
+		return true, nil
+	case "", "0", "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("decode checkbox field value: %s", v.Value)
+}
+
+// Date returns the value of date, date_time or birthday field, sent
+// by amoCRM either as Unix seconds or as a string in RFC 3339 format.
+func (v FieldValue) Date() (time.Time, error) {
+	var sec int64
+	if err := json.Unmarshal(v.Value, &sec); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+
+	s := v.String()
+	if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("decode date field value: %w", err)
+	}
+	return t, nil
+}
+
+// LegalEntity returns the value of legal_entity field.
+func (v FieldValue) LegalEntity() (LegalEntity, error) {
+	var e LegalEntity
+	return e, v.Decode(&e)
+}
+
+// LinkedEntity returns the value of linked_entity field.
+func (v FieldValue) LinkedEntity() (LinkedEntity, error) {
+	var e LinkedEntity
+	return e, v.Decode(&e)
+}
+
+// ChainedList returns the value of chained_list field.
+func (v FieldValue) ChainedList() (ChainedList, error) {
+	var c ChainedList
+	return c, v.Decode(&c)
+}
+
+// Decode decodes the raw value into dst.
+func (v FieldValue) Decode(dst interface{}) error {
+	if err := json.Unmarshal(v.Value, dst); err != nil {
+		return fmt.Errorf("decode field value: %w", err)
+	}
+	return nil
+}
+
+func (v FieldValue) isNull() bool {
+	b := bytes.TrimSpace(v.Value)
+	return len(b) == 0 || bytes.Equal(b, []byte("null"))
+}
+
+// mustMarshal encodes values that can't fail to encode.
+func mustMarshal(v interface{}) json.RawMessage {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm_test
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alexeykhan/amocrm"
+)
+
+func TestCustomFields_Decode(t *testing.T) {
+	body := `{"id": 1, "custom_fields_values": [
+		{"field_id": 1, "field_code": "PHONE", "field_type": "multitext", "values": [
+			{"value": "+79990000000", "enum_id": 11, "enum_code": "MOB"},
+			{"value": "+74950000000", "enum_id": 12, "enum_code": "WORK"}
+		]},
+		{"field_id": 2, "field_type": "numeric", "values": [{"value": "12.5"}]},
+		{"field_id": 3, "field_type": "price", "values": [{"value": 100}]},
+		{"field_id": 4, "field_type": "checkbox", "values": [{"value": true}]},
+		{"field_id": 5, "field_type": "date", "values": [{"value": 1600000000}]},
+		{"field_id": 6, "field_type": "date_time", "values": [{"value": "2020-09-13T15:26:40+03:00"}]},
+		{"field_id": 7, "field_type": "multiselect", "values": [
+			{"value": "Red", "enum_id": 71},
+			{"value": "Blue", "enum_id": 72}
+		]},
+		{"field_id": 8, "field_type": "legal_entity", "values": [{"value": {"name": "Acme", "vat_id": "7700000000", "kpp": "770001001"}}]},
+		{"field_id": 9, "field_type": "linked_entity", "values": [{"value": {"name": "Item", "entity_id": 91, "entity_type": "catalog_elements", "catalog_id": 92}}]},
+		{"field_id": 10, "field_type": "chained_list", "values": [{"value": {"catalog_id": 101, "catalog_element_id": 102}}]},
+		{"field_id": 11, "field_type": "smart_address", "values": [{"value": "Moscow", "enum_id": 1, "enum_code": "address_line_1"}]}
+	]}`
+
+	var contact amocrm.Contact
+	require.NoError(t, json.Unmarshal([]byte(body), &contact))
+	cf := contact.CustomFieldsValues
+
+	phones := cf.Phones()
+	require.Len(t, phones, 2)
+	require.Equal(t, "+79990000000", phones[0].String())
+	require.Equal(t, amocrm.EnumMobile, phones[0].EnumCode)
+	require.Equal(t, amocrm.EnumWork, phones[1].EnumCode)
+	require.Nil(t, cf.Emails())
+
+	number, err := cf.Values(2)[0].Float()
+	require.NoError(t, err)
+	require.Equal(t, 12.5, number)
+
+	price, err := cf.Values(3)[0].Float()
+	require.NoError(t, err)
+	require.Equal(t, 100.0, price)
+	require.Equal(t, "100", cf.Values(3)[0].String())
+
+	checked, err := cf.Values(4)[0].Bool()
+	require.NoError(t, err)
+	require.True(t, checked)
+
+	date, err := cf.Values(5)[0].Date()
+	require.NoError(t, err)
+	require.True(t, date.Equal(time.Unix(1600000000, 0)))
+
+	dateTime, err := cf.Values(6)[0].Date()
+	require.NoError(t, err)
+	require.True(t, dateTime.Equal(time.Unix(1600000000, 0)))
+
+	require.Equal(t, 72, cf.Values(7)[1].EnumID)
+	require.Equal(t, "Blue", cf.Values(7)[1].String())
+
+	legal, err := cf.Values(8)[0].LegalEntity()
+	require.NoError(t, err)
+	require.Equal(t, amocrm.LegalEntity{Name: "Acme", VatID: "7700000000", KPP: "770001001"}, legal)
+
+	linked, err := cf.Values(9)[0].LinkedEntity()
+	require.NoError(t, err)
+	require.Equal(t, amocrm.LinkedEntity{Name: "Item", EntityID: 91, EntityType: "catalog_elements", CatalogID: 92}, linked)
+
+	chained, err := cf.Values(10)[0].ChainedList()
+	require.NoError(t, err)
+	require.Equal(t, amocrm.ChainedList{CatalogID: 101, CatalogElementID: 102}, chained)
+
+	require.Equal(t, "address_line_1", cf.Values(11)[0].EnumCode)
+	require.Equal(t, "Moscow", cf.Values(11)[0].String())
+
+	require.Nil(t, cf.Field(404))
+	require.Nil(t, cf.Values(404))
+
+	_, err = cf.Values(8)[0].Float()
+	require.Error(t, err)
+	_, err = cf.Values(11)[0].Date()
+	require.Error(t, err)
+}
+
+func TestCustomFields_Encode(t *testing.T) {
+	var cf amocrm.CustomFields
+	cf.SetPhones(amocrm.MultitextValue("+79990000000", amocrm.EnumMobile))
+	cf.Set(2, amocrm.NumberValue(12.5))
+	cf.Set(4, amocrm.BoolValue(false))
+	cf.Set(5, amocrm.DateValue(time.Unix(1600000000, 0)))
+	cf.Set(7, amocrm.EnumValue(71), amocrm.EnumValue(72))
+	cf.Set(8, amocrm.LegalEntityValue(amocrm.LegalEntity{Name: "Acme"}))
+	cf.Set(10, amocrm.ChainedListValue(amocrm.ChainedList{CatalogID: 101, CatalogElementID: 102}))
+	cf.Set(12, amocrm.TextValue("old"))
+	cf.Set(12, amocrm.TextValue("new"))
+	cf.Clear(13)
+
+	body, err := json.Marshal(amocrm.Lead{ID: 1, CustomFieldsValues: cf})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id": 1, "custom_fields_values": [
+		{"field_code": "PHONE", "values": [{"value": "+79990000000", "enum_code": "MOB"}]},
+		{"field_id": 2, "values": [{"value": 12.5}]},
+		{"field_id": 4, "values": [{"value": false}]},
+		{"field_id": 5, "values": [{"value": 1600000000}]},
+		{"field_id": 7, "values": [{"enum_id": 71}, {"enum_id": 72}]},
+		{"field_id": 8, "values": [{"value": {"name": "Acme"}}]},
+		{"field_id": 10, "values": [{"value": {"catalog_id": 101, "catalog_element_id": 102}}]},
+		{"field_id": 12, "values": [{"value": "new"}]},
+		{"field_id": 13, "values": null}
+	]}`, string(body))
+
+	body, err = json.Marshal(amocrm.Lead{ID: 1})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id": 1}`, string(body))
+}
+
+func TestCustomFields_EncodeNonFinite(t *testing.T) {
+	for _, f := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		var cf amocrm.CustomFields
+		cf.Set(2, amocrm.NumberValue(f))
+
+		_, err := json.Marshal(amocrm.Lead{ID: 1, CustomFieldsValues: cf})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "number field value must be finite")
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+)
+
+// PlannedRequest is a write request captured in dry-run mode.
+type PlannedRequest struct {
+	Domain   string          `json:"domain"`
+	Method   string          `json:"method"`
+	Endpoint string          `json:"endpoint"`
+	Query    string          `json:"query,omitempty"`
+	Body     json.RawMessage `json:"body,omitempty"`
+}
+
+// Plan collects write requests a client in dry-run mode would have sent,
+// see WithDryRun. Plan is safe for concurrent use and may be shared
+// by several clients, e.g. by a Pool.
+type Plan struct {
+	mu       sync.Mutex
+	requests []PlannedRequest
+	lastID   int
+}
+
+// NewPlan allocates and returns a new empty Plan.
+func NewPlan() *Plan {
+	return &Plan{}
+}
+
+// Requests returns captured requests in the order they were made.
+func (p *Plan) Requests() []PlannedRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	requests := make([]PlannedRequest, len(p.requests))
+	copy(requests, p.requests)
+	return requests
+}
+
+// Reset forgets captured requests.
+func (p *Plan) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests = nil
+}
+
+// MarshalJSON exports captured requests as JSON array.
+func (p *Plan) MarshalJSON() ([]byte, error) {
+	requests := p.Requests()
+	if requests == nil {
+		requests = []PlannedRequest{}
+	}
+	return json.Marshal(requests)
+}
+
+// String formats captured requests for review, one
+// request line followed by indented body per request.
+func (p *Plan) String() string {
+	var b strings.Builder
+	for i, r := range p.Requests() {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+
+		target := r.Endpoint
+		if r.Query != "" {
+			target += "?" + r.Query
+		}
+		fmt.Fprintf(&b, "%s %s%s\n", r.Method, r.Domain, target)
+
+		if len(r.Body) > 0 {
+			var body bytes.Buffer
+			if err := json.Indent(&body, r.Body, "", "  "); err != nil {
+				body.Reset()
+				body.Write(r.Body)
+			}
+			b.Write(body.Bytes())
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+func (p *Plan) add(r PlannedRequest) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests = append(p.requests, r)
+}
+
+// nextID returns a synthetic ID for an entity that would have been
+// created. IDs are negative, so they never match real entities.
+func (p *Plan) nextID() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastID--
+	return p.lastID
+}
+
+// isWrite reports whether req changes data in amoCRM. Token requests
+// are not writes, so dry-run clients can still authorize. Any method
+// but safe ones is a write, so unexpected methods are never sent.
+func isWrite(req *Request) bool {
+	if req.Endpoint == tokenPath {
+		return false
+	}
+	switch strings.ToUpper(req.Method) {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
+
+// dryRun captures req in the plan and returns a synthetic response
+// echoing sent entities back under "_embedded", created ones getting
+// synthetic IDs, the way amoCRM responds to writes.
+func (a *api) dryRun(req *Request) (*http.Response, error) {
+	var payload []byte
+	if req.HTTP.Body != nil {
+		var err error
+		if payload, err = ioutil.ReadAll(req.HTTP.Body); err != nil {
+			return nil, fmt.Errorf("dry run: read request body: %w", err)
+		}
+		_ = req.HTTP.Body.Close()
+	}
+
+	a.plan.add(PlannedRequest{
+		Domain:   a.currentDomain(),
+		Method:   strings.ToUpper(req.Method),
+		Endpoint: req.Endpoint,
+		Query:    req.HTTP.URL.RawQuery,
+		Body:     json.RawMessage(payload),
+	})
+
+	resp := &http.Response{
+		Status:     "204 No Content",
+		StatusCode: http.StatusNoContent,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Body:       http.NoBody,
+		Request:    req.HTTP,
+	}
+
+	body, ok := a.plan.echo(path.Base(req.Endpoint), strings.ToUpper(req.Method), payload)
+	if ok {
+		resp.Status, resp.StatusCode = "200 OK", http.StatusOK
+		resp.Header.Set("Content-Type", "application/hal+json")
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		resp.ContentLength = int64(len(body))
+	}
+
+	return resp, nil
+}
+
+// echo builds a synthetic response body for entities in payload, which
+// is either an array of entities or a single one. It reports false
+// when payload has no entities to echo or holds something else.
+func (p *Plan) echo(entity, method string, payload []byte) ([]byte, bool) {
+	var items []map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &items); err != nil {
+		var item map[string]json.RawMessage
+		if err = json.Unmarshal(payload, &item); err != nil || item == nil {
+			return nil, false
+		}
+		items = append(items, item)
+	}
+	for _, item := range items {
+		if item == nil {
+			return nil, false
+		}
+	}
+
+	for _, item := range items {
+		if _, ok := item["id"]; !ok || method == http.MethodPost {
+			item["id"] = json.RawMessage(fmt.Sprintf("%d", p.nextID()))
+		}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"_embedded": map[string]interface{}{entity: items},
+	})
+	if err != nil {
+		return nil, false
+	}
+	return body, true
+}
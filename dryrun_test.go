@@ -0,0 +1,145 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alexeykhan/amocrm"
+)
+
+func TestWithDryRun(t *testing.T) {
+	var methods []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(`{"_embedded":{"leads":[{"id":1,"name":"Lead"}]}}`))
+	}))
+	defer srv.Close()
+
+	plan := amocrm.NewPlan()
+	cl := amocrm.New(clientID, clientSecret, redirectURL,
+		amocrm.WithBaseURL(srv.URL), amocrm.WithLimiter(nil), amocrm.WithDryRun(plan))
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+	require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Time{})))
+
+	it := cl.Leads().List(amocrm.LeadsConfig{})
+	require.True(t, it.Next(context.Background()))
+
+	result, err := cl.Leads().Create([]amocrm.Lead{{Name: "First"}, {Name: "Second"}})
+	require.NoError(t, err)
+	require.Equal(t, amocrm.BatchResult{{ID: -1}, {ID: -2}}, result)
+
+	result, err = cl.Contacts().Update([]amocrm.Contact{{ID: 7, Name: "Renamed"}})
+	require.NoError(t, err)
+	require.Equal(t, amocrm.BatchResult{{ID: 7}}, result)
+
+	require.Equal(t, []string{http.MethodGet}, methods)
+
+	requests := plan.Requests()
+	require.Len(t, requests, 2)
+	require.Equal(t, amocrm.PlannedRequest{
+		Domain:   "example.amocrm.ru",
+		Method:   http.MethodPost,
+		Endpoint: "/api/v4/leads",
+		Body:     json.RawMessage(`[{"name":"First","request_id":"0"},{"name":"Second","request_id":"1"}]`),
+	}, requests[0])
+	require.Equal(t, http.MethodPatch, requests[1].Method)
+	require.Equal(t, "/api/v4/contacts", requests[1].Endpoint)
+
+	data, err := json.Marshal(plan)
+	require.NoError(t, err)
+	var exported []amocrm.PlannedRequest
+	require.NoError(t, json.Unmarshal(data, &exported))
+	require.Equal(t, requests, exported)
+
+	require.Equal(t, `POST example.amocrm.ru/api/v4/leads
+[
+  {
+    "name": "First",
+    "request_id": "0"
+  },
+  {
+    "name": "Second",
+    "request_id": "1"
+  }
+]
+
+PATCH example.amocrm.ru/api/v4/contacts
+[
+  {
+    "id": 7,
+    "name": "Renamed",
+    "request_id": "0"
+  }
+]
+`, plan.String())
+
+	plan.Reset()
+	require.Empty(t, plan.Requests())
+	data, err = json.Marshal(plan)
+	require.NoError(t, err)
+	require.Equal(t, "[]", string(data))
+}
+
+func TestWithDryRun_Methods(t *testing.T) {
+	var methods []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	plan := amocrm.NewPlan()
+	cl := amocrm.New(clientID, clientSecret, redirectURL,
+		amocrm.WithBaseURL(srv.URL), amocrm.WithLimiter(nil), amocrm.WithDryRun(plan))
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+	require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Time{})))
+
+	body := []map[string]interface{}{{"id": 1, "name": "Renamed"}}
+	require.NoError(t, cl.Do("patch", "leads", nil, body, nil))
+
+	var out struct {
+		Embedded struct {
+			Leads []map[string]interface{} `json:"leads"`
+		} `json:"_embedded"`
+	}
+	require.NoError(t, cl.Do(http.MethodPost, "leads", nil, []interface{}{nil}, &out))
+	require.Empty(t, out.Embedded.Leads)
+
+	require.Empty(t, methods)
+
+	requests := plan.Requests()
+	require.Len(t, requests, 2)
+	require.Equal(t, http.MethodPatch, requests[0].Method)
+	require.Equal(t, "/api/v4/leads", requests[0].Endpoint)
+	require.Equal(t, json.RawMessage(`[null]`), requests[1].Body)
+}
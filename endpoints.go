@@ -24,14 +24,22 @@ package amocrm
 
 import (
 	"fmt"
+	"strings"
 )
 
+// endpoint is a path relative to the API version root, e.g. "leads",
+// or an absolute one, e.g. "/api/v2/account", which is used as is.
 type endpoint string
 
 func (e endpoint) path() string {
+	if strings.HasPrefix(string(e), "/") {
+		return string(e)
+	}
 	return fmt.Sprintf("/api/v%d/%s", apiVersion, e)
 }
 
 const (
-	accountsEndpoint endpoint = "accounts"
+	accountsEndpoint endpoint = "account"
+	leadsEndpoint    endpoint = "leads"
+	contactsEndpoint endpoint = "contacts"
 )
@@ -35,3 +35,8 @@ func TestEndpoint_Path(t *testing.T) {
 	require.Contains(t, path, "/api/v")
 	require.Contains(t, path, "/example")
 }
+
+func TestEndpoint_AbsolutePath(t *testing.T) {
+	require.Equal(t, "/api/v4/leads/pipelines", endpoint("leads/pipelines").path())
+	require.Equal(t, "/api/v2/account", endpoint("/api/v2/account").path())
+}
@@ -24,56 +24,75 @@ package amocrm
 
 // Account represents amoCRM Account entity json DTO.
 type Account struct {
-	ID                      int    `json:"id"`
-	Name                    string `json:"name"`
-	Subdomain               string `json:"subdomain"`
-	CreatedAt               int    `json:"created_at"`
-	CreatedBy               int    `json:"created_by"`
-	UpdatedAt               int    `json:"updated_at"`
-	UpdatedBy               int    `json:"updated_by"`
-	CurrentUserID           int    `json:"current_user_id"`
-	Country                 string `json:"country"`
-	Currency                string `json:"currency"`
-	CustomersMode           string `json:"customers_mode"`
-	IsUnsortedOn            bool   `json:"is_unsorted_on"`
-	MobileFeatureVersion    int    `json:"mobile_feature_version"`
-	IsLossReasonEnabled     bool   `json:"is_loss_reason_enabled"`
-	IsHelpbotEnabled        bool   `json:"is_helpbot_enabled"`
-	IsTechnicalAccount      bool   `json:"is_technical_account"`
-	ContactNameDisplayOrder int    `json:"contact_name_display_order"`
-	AmojoID                 string `json:"amojo_id"`
-	UUID                    string `json:"uuid"`
-	Version                 int    `json:"version"`
-	Links                   struct {
-		Self struct {
-			Href string `json:"href"`
-		} `json:"self"`
-	} `json:"_links"`
-	Embedded struct {
-		AmojoRights struct {
-			CanDirect       bool `json:"can_direct"`
-			CanCreateGroups bool `json:"can_create_groups"`
-		} `json:"amojo_rights"`
-		UsersGroups []struct {
-			ID   int         `json:"id"`
-			Name string      `json:"name"`
-			UUID interface{} `json:"uuid"`
-		} `json:"users_groups"`
-		TaskTypes []struct {
-			ID     int         `json:"id"`
-			Name   string      `json:"name"`
-			Color  interface{} `json:"color"`
-			IconID interface{} `json:"icon_id"`
-			Code   string      `json:"code"`
-		} `json:"task_types"`
-		DatetimeSettings struct {
-			DatePattern      string `json:"date_pattern"`
-			ShortDatePattern string `json:"short_date_pattern"`
-			ShortTimePattern string `json:"short_time_pattern"`
-			DateFormat       string `json:"date_format"`
-			TimeFormat       string `json:"time_format"`
-			Timezone         string `json:"timezone"`
-			TimezoneOffset   string `json:"timezone_offset"`
-		} `json:"datetime_settings"`
-	} `json:"_embedded"`
+	ID                      int             `json:"id"`
+	Name                    string          `json:"name"`
+	Subdomain               string          `json:"subdomain"`
+	CreatedAt               Timestamp       `json:"created_at"`
+	CreatedBy               int             `json:"created_by"`
+	UpdatedAt               Timestamp       `json:"updated_at"`
+	UpdatedBy               int             `json:"updated_by"`
+	CurrentUserID           int             `json:"current_user_id"`
+	Country                 string          `json:"country"`
+	Currency                string          `json:"currency"`
+	CustomersMode           string          `json:"customers_mode"`
+	IsUnsortedOn            bool            `json:"is_unsorted_on"`
+	MobileFeatureVersion    int             `json:"mobile_feature_version"`
+	IsLossReasonEnabled     bool            `json:"is_loss_reason_enabled"`
+	IsHelpbotEnabled        bool            `json:"is_helpbot_enabled"`
+	IsTechnicalAccount      bool            `json:"is_technical_account"`
+	ContactNameDisplayOrder int             `json:"contact_name_display_order"`
+	AmojoID                 string          `json:"amojo_id"`
+	UUID                    string          `json:"uuid"`
+	Version                 int             `json:"version"`
+	Links                   Links           `json:"_links"`
+	Embedded                AccountEmbedded `json:"_embedded"`
+}
+
+// Lead represents amoCRM Lead entity json DTO. Zero fields are
+// omitted when the lead is sent to amoCRM.
+type Lead struct {
+	ID                 int           `json:"id,omitempty"`
+	Name               string        `json:"name,omitempty"`
+	Price              int           `json:"price,omitempty"`
+	ResponsibleUserID  int           `json:"responsible_user_id,omitempty"`
+	GroupID            int           `json:"group_id,omitempty"`
+	StatusID           int           `json:"status_id,omitempty"`
+	PipelineID         int           `json:"pipeline_id,omitempty"`
+	LossReasonID       int           `json:"loss_reason_id,omitempty"`
+	SourceID           int           `json:"source_id,omitempty"`
+	CreatedBy          int           `json:"created_by,omitempty"`
+	UpdatedBy          int           `json:"updated_by,omitempty"`
+	CreatedAt          Timestamp     `json:"created_at,omitempty"`
+	UpdatedAt          Timestamp     `json:"updated_at,omitempty"`
+	ClosedAt           Timestamp     `json:"closed_at,omitempty"`
+	ClosestTaskAt      Timestamp     `json:"closest_task_at,omitempty"`
+	IsDeleted          bool          `json:"is_deleted,omitempty"`
+	Score              int           `json:"score,omitempty"`
+	AccountID          int           `json:"account_id,omitempty"`
+	CustomFieldsValues CustomFields  `json:"custom_fields_values,omitempty"`
+	RequestID          string        `json:"request_id,omitempty"`
+	Links              *Links        `json:"_links,omitempty"`
+	Embedded           *LeadEmbedded `json:"_embedded,omitempty"`
+}
+
+// Contact represents amoCRM Contact entity json DTO. Zero fields
+// are omitted when the contact is sent to amoCRM.
+type Contact struct {
+	ID                 int              `json:"id,omitempty"`
+	Name               string           `json:"name,omitempty"`
+	FirstName          string           `json:"first_name,omitempty"`
+	LastName           string           `json:"last_name,omitempty"`
+	ResponsibleUserID  int              `json:"responsible_user_id,omitempty"`
+	GroupID            int              `json:"group_id,omitempty"`
+	CreatedBy          int              `json:"created_by,omitempty"`
+	UpdatedBy          int              `json:"updated_by,omitempty"`
+	CreatedAt          Timestamp        `json:"created_at,omitempty"`
+	UpdatedAt          Timestamp        `json:"updated_at,omitempty"`
+	ClosestTaskAt      Timestamp        `json:"closest_task_at,omitempty"`
+	IsDeleted          bool             `json:"is_deleted,omitempty"`
+	AccountID          int              `json:"account_id,omitempty"`
+	CustomFieldsValues CustomFields     `json:"custom_fields_values,omitempty"`
+	RequestID          string           `json:"request_id,omitempty"`
+	Links              *Links           `json:"_links,omitempty"`
+	Embedded           *ContactEmbedded `json:"_embedded,omitempty"`
 }
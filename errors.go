@@ -0,0 +1,174 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// APIError is returned when amoCRM responds with non-2xx status code.
+// It holds the problem+json payload amoCRM attaches to error responses.
+type APIError struct {
+	StatusCode       int               `json:"-"`
+	Title            string            `json:"title"`
+	Type             string            `json:"type"`
+	Detail           string            `json:"detail"`
+	Hint             string            `json:"hint"`
+	ValidationErrors []ValidationError `json:"validation-errors"`
+
+	// RetryAfter is a delay amoCRM asked to wait before
+	// the next request, if any.
+	RetryAfter time.Duration `json:"-"`
+}
+
+// ValidationError groups field errors of a single entity in request.
+type ValidationError struct {
+	RequestID string       `json:"request_id"`
+	Errors    []FieldError `json:"errors"`
+}
+
+// FieldError describes why amoCRM rejected a value at the given path.
+type FieldError struct {
+	Code   string `json:"code"`
+	Path   string `json:"path"`
+	Detail string `json:"detail"`
+}
+
+// Error implements error interface.
+func (e *APIError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "amocrm: %d %s", e.StatusCode, e.Title)
+	if e.Detail != "" {
+		b.WriteString(": " + e.Detail)
+	}
+	if e.Hint != "" {
+		b.WriteString(" (" + e.Hint + ")")
+	}
+	for _, ve := range e.ValidationErrors {
+		for _, fe := range ve.Errors {
+			fmt.Fprintf(&b, "; request %s: %s: %s", ve.RequestID, fe.Path, fe.Detail)
+		}
+	}
+	return b.String()
+}
+
+// newAPIError builds an APIError from status code and response body.
+// Body that is not a valid problem+json leaves optional fields empty.
+func newAPIError(statusCode int, body []byte) *APIError {
+	apiErr := &APIError{}
+	_ = json.Unmarshal(body, apiErr)
+
+	apiErr.StatusCode = statusCode
+	if apiErr.Title == "" {
+		apiErr.Title = http.StatusText(statusCode)
+	}
+
+	return apiErr
+}
+
+// checkResponse returns APIError for non-2xx responses closing the body.
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return nil
+	}
+
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	_ = resp.Body.Close()
+
+	apiErr := newAPIError(resp.StatusCode, body)
+	apiErr.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+
+	return apiErr
+}
+
+// UnauthorizedError is returned when amoCRM rejects requests with 401
+// even after the token was refreshed, or the token can't be refreshed
+// at all, e.g. because the integration was disabled in the account.
+// The user must authorize the integration again to get a new token.
+type UnauthorizedError struct {
+	// Err is the last 401 response as *APIError, nil when expired
+	// token was rejected before any request was made with it.
+	Err error
+	// RefreshErr is the error of rejected token refresh, if any.
+	RefreshErr error
+}
+
+// Error implements error interface.
+func (e *UnauthorizedError) Error() string {
+	msg := "amocrm: authorization required again"
+	if e.Err != nil {
+		msg += fmt.Sprintf(": %v", e.Err)
+	}
+	if e.RefreshErr != nil {
+		msg += fmt.Sprintf("; refresh token: %v", e.RefreshErr)
+	}
+	return msg
+}
+
+// Unwrap returns the 401 response error, if any.
+func (e *UnauthorizedError) Unwrap() error {
+	return e.Err
+}
+
+// IsReauthRequired reports whether err is an UnauthorizedError, so
+// the user must authorize the integration again.
+func IsReauthRequired(err error) bool {
+	var authErr *UnauthorizedError
+	return errors.As(err, &authErr)
+}
+
+// IsNotFound reports whether err is an APIError with 404 status code.
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsUnauthorized reports whether err is an APIError with 401 status
+// code or an UnauthorizedError.
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized) || IsReauthRequired(err)
+}
+
+// IsRateLimited reports whether err is an APIError with 429 status code.
+func IsRateLimited(err error) bool {
+	return hasStatus(err, http.StatusTooManyRequests)
+}
+
+// IsValidation reports whether err is an APIError caused by invalid
+// request data, i.e. it has 400 status code or validation errors.
+func IsValidation(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusBadRequest || len(apiErr.ValidationErrors) > 0
+}
+
+func hasStatus(err error, statusCode int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == statusCode
+}
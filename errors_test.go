@@ -0,0 +1,132 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alexeykhan/amocrm"
+)
+
+func TestAPIError(t *testing.T) {
+	cases := []struct {
+		status       int
+		body         string
+		notFound     bool
+		unauthorized bool
+		rateLimited  bool
+		validation   bool
+		message      string
+	}{
+		{
+			status:       http.StatusUnauthorized,
+			body:         `{"title":"Unauthorized","type":"https://httpstatus.es/401","status":401,"detail":"Incorrect account"}`,
+			unauthorized: true,
+			message: "get accounts: amocrm: authorization required again: amocrm: 401 Unauthorized: Incorrect account; " +
+				"refresh token: oauth2: fetch token: amocrm: 401 Unauthorized: Incorrect account",
+		},
+		{
+			status:   http.StatusNotFound,
+			body:     ``,
+			notFound: true,
+			message:  "get accounts: amocrm: 404 Not Found",
+		},
+		{
+			status:      http.StatusTooManyRequests,
+			body:        `<html>Too many requests</html>`,
+			rateLimited: true,
+			message:     "get accounts: amocrm: 429 Too Many Requests",
+		},
+		{
+			status: http.StatusBadRequest,
+			body: `{"title":"Bad Request","type":"https://httpstatus.es/400","status":400,"detail":"Request validation failed",` +
+				`"validation-errors":[{"request_id":"0","errors":[{"code":"NotSupportedChoice","path":"with","detail":"Invalid value"}]}]}`,
+			validation: true,
+			message:    "get accounts: amocrm: 400 Bad Request: Request validation failed; request 0: with: Invalid value",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(tc.status)
+			_, _ = w.Write([]byte(tc.body))
+		}))
+
+		cl := amocrm.New(clientID, clientSecret, redirectURL, amocrm.WithBaseURL(srv.URL))
+		_ = cl.SetDomain("example.amocrm.ru")
+		_ = cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Time{}))
+
+		account, err := cl.Accounts().Current(amocrm.AccountsConfig{})
+		srv.Close()
+
+		require.Nil(t, account)
+		require.EqualError(t, err, tc.message)
+		require.Equal(t, tc.notFound, amocrm.IsNotFound(err))
+		require.Equal(t, tc.unauthorized, amocrm.IsUnauthorized(err))
+		require.Equal(t, tc.rateLimited, amocrm.IsRateLimited(err))
+		require.Equal(t, tc.validation, amocrm.IsValidation(err))
+
+		var apiErr *amocrm.APIError
+		require.True(t, errors.As(err, &apiErr))
+		require.Equal(t, tc.status, apiErr.StatusCode)
+	}
+}
+
+func TestAPIError_Validation(t *testing.T) {
+	apiErr := &amocrm.APIError{
+		StatusCode: http.StatusBadRequest,
+		Title:      "Bad Request",
+		ValidationErrors: []amocrm.ValidationError{{
+			RequestID: "1",
+			Errors:    []amocrm.FieldError{{Code: "NotBlank", Path: "name", Detail: "This value should not be blank."}},
+		}},
+	}
+
+	err := fmt.Errorf("create leads: %w", apiErr)
+	require.True(t, amocrm.IsValidation(err))
+	require.False(t, amocrm.IsValidation(errors.New("bad request")))
+	require.False(t, amocrm.IsNotFound(nil))
+}
+
+func TestAPIError_TokenByCode(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"hint":"Authorization code has expired","title":"Invalid grant","status":400}`))
+	}))
+	defer srv.Close()
+
+	cl := amocrm.New(clientID, clientSecret, redirectURL, amocrm.WithBaseURL(srv.URL))
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+
+	_, err := cl.TokenByCode("code")
+	require.EqualError(t, err, "oauth2: fetch token: amocrm: 400 Invalid grant (Authorization code has expired)")
+	require.True(t, amocrm.IsValidation(err))
+	require.NotContains(t, err.Error(), clientSecret)
+}
@@ -0,0 +1,273 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Sort directions of list endpoints.
+const (
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+// Filter builds filter, order and search parameters of list endpoints
+// in amoCRM bracket syntax, e.g. "filter[created_at][from]". Methods
+// return the Filter itself, so calls can be chained:
+//
+//	f := amocrm.NewFilter().
+//		CreatedAt(from, to).
+//		Status(pipelineID, statusID).
+//		OrderBy("updated_at", amocrm.OrderDesc)
+//
+// Filters not supported by the requested entity are reported
+// as an error by the list method. Zero Filter is ready to use.
+type Filter struct {
+	values   url.Values
+	used     []string
+	orders   []string
+	statuses int
+	err      error
+}
+
+// NewFilter allocates and returns a new empty Filter.
+func NewFilter() *Filter {
+	return &Filter{values: url.Values{}}
+}
+
+// IDs filters entities by IDs.
+func (f *Filter) IDs(ids ...int) *Filter {
+	return f.ints("id", ids)
+}
+
+// Names filters entities by names.
+func (f *Filter) Names(names ...string) *Filter {
+	f.use("name")
+	for _, name := range names {
+		f.add("filter[name][]", name)
+	}
+	return f
+}
+
+// Price filters leads with price in the range. Zero to means no upper bound.
+func (f *Filter) Price(from, to int) *Filter {
+	f.use("price")
+	f.set("filter[price][from]", strconv.Itoa(from))
+	if to > 0 {
+		f.set("filter[price][to]", strconv.Itoa(to))
+	}
+	return f
+}
+
+// CreatedBy filters entities by IDs of users who created them.
+func (f *Filter) CreatedBy(userIDs ...int) *Filter {
+	return f.ints("created_by", userIDs)
+}
+
+// UpdatedBy filters entities by IDs of users who updated them last.
+func (f *Filter) UpdatedBy(userIDs ...int) *Filter {
+	return f.ints("updated_by", userIDs)
+}
+
+// ResponsibleUsers filters entities by IDs of responsible users.
+func (f *Filter) ResponsibleUsers(userIDs ...int) *Filter {
+	return f.ints("responsible_user_id", userIDs)
+}
+
+// PipelineIDs filters leads by IDs of pipelines.
+func (f *Filter) PipelineIDs(ids ...int) *Filter {
+	return f.ints("pipeline_id", ids)
+}
+
+// Status filters leads by status in the pipeline. Call it
+// several times to get leads having any of the statuses.
+func (f *Filter) Status(pipelineID, statusID int) *Filter {
+	f.use("statuses")
+	prefix := fmt.Sprintf("filter[statuses][%d]", f.statuses)
+	f.set(prefix+"[pipeline_id]", strconv.Itoa(pipelineID))
+	f.set(prefix+"[status_id]", strconv.Itoa(statusID))
+	f.statuses++
+	return f
+}
+
+// CreatedAt filters entities created in the time range.
+// Zero from or to means the range is open from that side.
+func (f *Filter) CreatedAt(from, to time.Time) *Filter {
+	return f.timeRange("created_at", from, to)
+}
+
+// UpdatedAt filters entities updated in the time range.
+// Zero from or to means the range is open from that side.
+func (f *Filter) UpdatedAt(from, to time.Time) *Filter {
+	return f.timeRange("updated_at", from, to)
+}
+
+// ClosedAt filters leads closed in the time range.
+// Zero from or to means the range is open from that side.
+func (f *Filter) ClosedAt(from, to time.Time) *Filter {
+	return f.timeRange("closed_at", from, to)
+}
+
+// ClosestTaskAt filters entities by time of the closest task.
+// Zero from or to means the range is open from that side.
+func (f *Filter) ClosestTaskAt(from, to time.Time) *Filter {
+	return f.timeRange("closest_task_at", from, to)
+}
+
+// CustomField filters entities having any of the values
+// in the custom field, e.g. enum IDs of a select field.
+func (f *Filter) CustomField(fieldID int, values ...string) *Filter {
+	f.use("custom_fields_values")
+	key := fmt.Sprintf("filter[custom_fields_values][%d][]", fieldID)
+	for _, v := range values {
+		f.add(key, v)
+	}
+	return f
+}
+
+// CustomFieldRange filters entities having numeric or
+// date custom field value in the range.
+func (f *Filter) CustomFieldRange(fieldID int, from, to int64) *Filter {
+	f.use("custom_fields_values")
+	prefix := fmt.Sprintf("filter[custom_fields_values][%d]", fieldID)
+	f.set(prefix+"[from]", strconv.FormatInt(from, 10))
+	f.set(prefix+"[to]", strconv.FormatInt(to, 10))
+	return f
+}
+
+// Query searches entities by the text in all their fields.
+func (f *Filter) Query(query string) *Filter {
+	f.use("query")
+	f.set("query", query)
+	return f
+}
+
+// OrderBy sorts entities by the field in the direction,
+// either OrderAsc or OrderDesc.
+func (f *Filter) OrderBy(field, direction string) *Filter {
+	if direction != OrderAsc && direction != OrderDesc {
+		f.fail(fmt.Errorf("unexpected order direction: %s", direction))
+		return f
+	}
+	f.orders = append(f.orders, field)
+	f.set("order["+field+"]", direction)
+	return f
+}
+
+// filterSpec lists filters and order fields supported by an entity.
+type filterSpec struct {
+	entity  string
+	filters map[string]bool
+	orders  map[string]bool
+}
+
+// apply validates filter against spec and adds its parameters to query.
+func (f *Filter) apply(spec filterSpec, query url.Values) error {
+	if f == nil {
+		return nil
+	}
+	if f.err != nil {
+		return f.err
+	}
+
+	for _, name := range f.used {
+		if !spec.filters[name] {
+			return fmt.Errorf("unexpected %s filter: %s", spec.entity, name)
+		}
+	}
+	for _, field := range f.orders {
+		if !spec.orders[field] {
+			return fmt.Errorf("unexpected %s order field: %s", spec.entity, field)
+		}
+	}
+
+	for k, v := range f.values {
+		query[k] = append(query[k], v...)
+	}
+	return nil
+}
+
+func (f *Filter) use(name string) {
+	for _, used := range f.used {
+		if used == name {
+			return
+		}
+	}
+	f.used = append(f.used, name)
+}
+
+// add appends value to the parameter key.
+func (f *Filter) add(key, value string) {
+	if f.values == nil {
+		f.values = url.Values{}
+	}
+	f.values.Add(key, value)
+}
+
+// set replaces values of the parameter key with value.
+func (f *Filter) set(key, value string) {
+	if f.values == nil {
+		f.values = url.Values{}
+	}
+	f.values.Set(key, value)
+}
+
+func (f *Filter) fail(err error) {
+	if f.err == nil {
+		f.err = err
+	}
+}
+
+func (f *Filter) ints(name string, values []int) *Filter {
+	f.use(name)
+	for _, v := range values {
+		f.add("filter["+name+"][]", strconv.Itoa(v))
+	}
+	return f
+}
+
+func (f *Filter) timeRange(name string, from, to time.Time) *Filter {
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		f.fail(fmt.Errorf("invalid %s range: %s is before %s", name, to, from))
+		return f
+	}
+
+	f.use(name)
+	if !from.IsZero() {
+		f.set("filter["+name+"][from]", strconv.FormatInt(from.Unix(), 10))
+	}
+	if !to.IsZero() {
+		f.set("filter["+name+"][to]", strconv.FormatInt(to.Unix(), 10))
+	}
+	return f
+}
+
+func stringSet(keys ...string) map[string]bool {
+	m := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		m[k] = true
+	}
+	return m
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alexeykhan/amocrm"
+)
+
+func TestFilter(t *testing.T) {
+	var query url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		_, _ = w.Write([]byte(`{"_embedded":{"leads":[]}}`))
+	}))
+	defer srv.Close()
+
+	cl := amocrm.New(clientID, clientSecret, redirectURL, amocrm.WithBaseURL(srv.URL), amocrm.WithLimiter(nil))
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+	require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Time{})))
+
+	from := time.Unix(1600000000, 0)
+	to := time.Unix(1700000000, 0)
+
+	it := cl.Leads().List(amocrm.LeadsConfig{Filter: amocrm.NewFilter().
+		IDs(1, 2).
+		CreatedAt(from, to).
+		UpdatedAt(from, time.Time{}).
+		CustomField(100, "a", "b").
+		Status(10, 11).
+		Status(20, 21).
+		Query("acme").
+		OrderBy("updated_at", amocrm.OrderDesc),
+	})
+	require.False(t, it.Next(context.Background()))
+	require.NoError(t, it.Err())
+
+	require.Equal(t, url.Values{
+		"filter[id][]":                        {"1", "2"},
+		"filter[created_at][from]":            {"1600000000"},
+		"filter[created_at][to]":              {"1700000000"},
+		"filter[updated_at][from]":            {"1600000000"},
+		"filter[custom_fields_values][100][]": {"a", "b"},
+		"filter[statuses][0][pipeline_id]":    {"10"},
+		"filter[statuses][0][status_id]":      {"11"},
+		"filter[statuses][1][pipeline_id]":    {"20"},
+		"filter[statuses][1][status_id]":      {"21"},
+		"query":                               {"acme"},
+		"order[updated_at]":                   {"desc"},
+	}, query)
+}
+
+func TestFilter_Zero(t *testing.T) {
+	var query url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	cl := amocrm.New(clientID, clientSecret, redirectURL, amocrm.WithBaseURL(srv.URL), amocrm.WithLimiter(nil))
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+	require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Time{})))
+
+	var f amocrm.Filter
+	f.IDs(1).Names("Acme").Query("acme")
+
+	it := cl.Contacts().List(amocrm.ContactsConfig{Filter: &f})
+	require.False(t, it.Next(context.Background()))
+	require.NoError(t, it.Err())
+	require.Equal(t, url.Values{
+		"filter[id][]":   {"1"},
+		"filter[name][]": {"Acme"},
+		"query":          {"acme"},
+	}, query)
+
+	it = cl.Contacts().List(amocrm.ContactsConfig{Filter: &amocrm.Filter{}})
+	require.False(t, it.Next(context.Background()))
+	require.NoError(t, it.Err())
+}
+
+func TestFilter_Invalid(t *testing.T) {
+	cl := amocrm.New(clientID, clientSecret, redirectURL)
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+	require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Time{})))
+
+	now := time.Now()
+	tests := []struct {
+		name string
+		list func() *amocrm.Iterator
+		err  string
+	}{
+		{
+			name: "unsupported filter",
+			list: func() *amocrm.Iterator {
+				return cl.Contacts().List(amocrm.ContactsConfig{Filter: amocrm.NewFilter().PipelineIDs(1)})
+			},
+			err: "unexpected contact filter: pipeline_id",
+		},
+		{
+			name: "unsupported order field",
+			list: func() *amocrm.Iterator {
+				return cl.Contacts().List(amocrm.ContactsConfig{Filter: amocrm.NewFilter().OrderBy("created_at", amocrm.OrderAsc)})
+			},
+			err: "unexpected contact order field: created_at",
+		},
+		{
+			name: "invalid direction",
+			list: func() *amocrm.Iterator {
+				return cl.Leads().List(amocrm.LeadsConfig{Filter: amocrm.NewFilter().OrderBy("id", "up")})
+			},
+			err: "unexpected order direction: up",
+		},
+		{
+			name: "inverted range",
+			list: func() *amocrm.Iterator {
+				return cl.Leads().List(amocrm.LeadsConfig{Filter: amocrm.NewFilter().ClosedAt(now, now.Add(-time.Hour))})
+			},
+			err: "invalid closed_at range",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			it := tt.list()
+			require.False(t, it.Next(context.Background()))
+			require.Error(t, it.Err())
+			require.Contains(t, it.Err().Error(), tt.err)
+		})
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// Link is a HAL link to amoCRM resource.
+type Link struct {
+	Href string `json:"href"`
+}
+
+// Links holds HAL links of an entity or a page, found in "_links".
+// Page links other than self are set only when the page exists.
+type Links struct {
+	Self  Link  `json:"self"`
+	First *Link `json:"first,omitempty"`
+	Prev  *Link `json:"prev,omitempty"`
+	Next  *Link `json:"next,omitempty"`
+}
+
+// Page is an envelope of a single page of amoCRM list endpoint. Items
+// are kept as raw JSON under the name of the entity, e.g. "leads".
+type Page struct {
+	Page     int                        `json:"_page"`
+	Links    Links                      `json:"_links"`
+	Embedded map[string]json.RawMessage `json:"_embedded"`
+}
+
+// Decode decodes items embedded as entity into v, which
+// is usually a pointer to a slice, e.g. *[]Lead.
+// Missing entity leaves v untouched.
+func (p *Page) Decode(entity string, v interface{}) error {
+	raw, ok := p.Embedded[entity]
+	if !ok {
+		return nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("decode %s page: %w", entity, err)
+	}
+	return nil
+}
+
+// NextQuery returns query of the next page link,
+// or nil if the page is the last one.
+func (p *Page) NextQuery() (url.Values, error) {
+	if p.Links.Next == nil || p.Links.Next.Href == "" {
+		return nil, nil
+	}
+
+	next, err := url.Parse(p.Links.Next.Href)
+	if err != nil {
+		return nil, fmt.Errorf("parse next page link: %w", err)
+	}
+	return next.Query(), nil
+}
+
+// Tag is a tag embedded into an entity.
+type Tag struct {
+	ID    int    `json:"id,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Color string `json:"color,omitempty"`
+}
+
+// LinkedContact is a contact embedded into a lead or a company.
+type LinkedContact struct {
+	ID     int  `json:"id"`
+	IsMain bool `json:"is_main,omitempty"`
+}
+
+// LinkedCompany is a company embedded into a lead or a contact.
+type LinkedCompany struct {
+	ID int `json:"id"`
+}
+
+// LinkedLead is a lead embedded into a contact or a company.
+type LinkedLead struct {
+	ID int `json:"id"`
+}
+
+// LinkedCustomer is a customer embedded into a contact or a company.
+type LinkedCustomer struct {
+	ID int `json:"id"`
+}
+
+// CatalogElement is a catalog element, e.g. a product, linked to an entity.
+type CatalogElement struct {
+	ID       int                     `json:"id"`
+	Metadata *CatalogElementMetadata `json:"metadata,omitempty"`
+}
+
+// CatalogElementMetadata describes how the catalog element is linked.
+type CatalogElementMetadata struct {
+	Quantity  float64 `json:"quantity,omitempty"`
+	CatalogID int     `json:"catalog_id,omitempty"`
+	PriceID   int     `json:"price_id,omitempty"`
+}
+
+// LossReason is a reason the lead was lost for.
+type LossReason struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Sort      int       `json:"sort,omitempty"`
+	CreatedAt Timestamp `json:"created_at,omitempty"`
+	UpdatedAt Timestamp `json:"updated_at,omitempty"`
+	Links     *Links    `json:"_links,omitempty"`
+}
+
+// TaskType is a type of tasks available in the account.
+type TaskType struct {
+	ID     int         `json:"id"`
+	Name   string      `json:"name"`
+	Color  interface{} `json:"color"`
+	IconID interface{} `json:"icon_id"`
+	Code   string      `json:"code"`
+}
+
+// UsersGroup is a group of users of the account.
+type UsersGroup struct {
+	ID   int         `json:"id"`
+	Name string      `json:"name"`
+	UUID interface{} `json:"uuid"`
+}
+
+// AmojoRights describes chat permissions of the current user.
+type AmojoRights struct {
+	CanDirect       bool `json:"can_direct"`
+	CanCreateGroups bool `json:"can_create_groups"`
+}
+
+// DatetimeSettings describes date and time formats of the account.
+type DatetimeSettings struct {
+	DatePattern      string `json:"date_pattern"`
+	ShortDatePattern string `json:"short_date_pattern"`
+	ShortTimePattern string `json:"short_time_pattern"`
+	DateFormat       string `json:"date_format"`
+	TimeFormat       string `json:"time_format"`
+	Timezone         string `json:"timezone"`
+	TimezoneOffset   string `json:"timezone_offset"`
+}
+
+// AccountEmbedded holds resources embedded into Account
+// when requested with the corresponding relations.
+type AccountEmbedded struct {
+	AmojoRights      AmojoRights      `json:"amojo_rights"`
+	UsersGroups      []UsersGroup     `json:"users_groups"`
+	TaskTypes        []TaskType       `json:"task_types"`
+	DatetimeSettings DatetimeSettings `json:"datetime_settings"`
+}
+
+// LeadEmbedded holds resources embedded into Lead.
+type LeadEmbedded struct {
+	Tags            []Tag            `json:"tags,omitempty"`
+	Contacts        []LinkedContact  `json:"contacts,omitempty"`
+	Companies       []LinkedCompany  `json:"companies,omitempty"`
+	CatalogElements []CatalogElement `json:"catalog_elements,omitempty"`
+	LossReason      []LossReason     `json:"loss_reason,omitempty"`
+}
+
+// ContactEmbedded holds resources embedded into Contact.
+type ContactEmbedded struct {
+	Tags            []Tag            `json:"tags,omitempty"`
+	Companies       []LinkedCompany  `json:"companies,omitempty"`
+	Leads           []LinkedLead     `json:"leads,omitempty"`
+	Customers       []LinkedCustomer `json:"customers,omitempty"`
+	CatalogElements []CatalogElement `json:"catalog_elements,omitempty"`
+}
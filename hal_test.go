@@ -0,0 +1,84 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm_test
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alexeykhan/amocrm"
+)
+
+func TestPage(t *testing.T) {
+	body := `{
+		"_page": 2,
+		"_links": {
+			"self": {"href": "https://example.amocrm.ru/api/v4/leads?page=2"},
+			"next": {"href": "https://example.amocrm.ru/api/v4/leads?page=3&limit=50"}
+		},
+		"_embedded": {"leads": [{
+			"id": 1,
+			"_links": {"self": {"href": "https://example.amocrm.ru/api/v4/leads/1"}},
+			"_embedded": {
+				"tags": [{"id": 5, "name": "vip"}],
+				"catalog_elements": [{"id": 7, "metadata": {"quantity": 2.5, "catalog_id": 8}}],
+				"loss_reason": [{"id": 9, "name": "Too expensive"}]
+			}
+		}]}
+	}`
+
+	var p amocrm.Page
+	require.NoError(t, json.Unmarshal([]byte(body), &p))
+	require.Equal(t, 2, p.Page)
+	require.Equal(t, "https://example.amocrm.ru/api/v4/leads?page=2", p.Links.Self.Href)
+	require.Nil(t, p.Links.Prev)
+
+	next, err := p.NextQuery()
+	require.NoError(t, err)
+	require.Equal(t, url.Values{"page": {"3"}, "limit": {"50"}}, next)
+
+	var leads []amocrm.Lead
+	require.NoError(t, p.Decode("leads", &leads))
+	require.Len(t, leads, 1)
+	require.Equal(t, "https://example.amocrm.ru/api/v4/leads/1", leads[0].Links.Self.Href)
+	require.Equal(t, []amocrm.Tag{{ID: 5, Name: "vip"}}, leads[0].Embedded.Tags)
+	require.Equal(t, 2.5, leads[0].Embedded.CatalogElements[0].Metadata.Quantity)
+	require.Equal(t, "Too expensive", leads[0].Embedded.LossReason[0].Name)
+
+	var contacts []amocrm.Contact
+	require.NoError(t, p.Decode("contacts", &contacts))
+	require.Nil(t, contacts)
+
+	var count int
+	require.Error(t, p.Decode("leads", &count))
+}
+
+func TestPage_LastPage(t *testing.T) {
+	var p amocrm.Page
+	require.NoError(t, json.Unmarshal([]byte(`{"_links": {"self": {"href": "/api/v4/leads"}}}`), &p))
+
+	next, err := p.NextQuery()
+	require.NoError(t, err)
+	require.Nil(t, next)
+}
@@ -0,0 +1,191 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Iterator walks through items of amoCRM list endpoint page by page,
+// following "_links.next" until there are no more pages. Empty 204 No
+// Content response ends the iteration. Iterator is not safe for
+// concurrent use.
+//
+//	it := amoCRM.Leads().List(cfg)
+//	for it.Next(ctx) {
+//		var lead amocrm.Lead
+//		if err := it.Decode(&lead); err != nil {
+//			return err
+//		}
+//	}
+//	if err := it.Err(); err != nil {
+//		return err
+//	}
+type Iterator struct {
+	api    *api
+	ep     endpoint
+	entity string
+
+	// query of the next page, nil if there is none.
+	query url.Values
+	items []json.RawMessage
+	cur   json.RawMessage
+	err   error
+	// tail is reported by Err once items run out.
+	tail error
+}
+
+// newIterator returns Iterator over items embedded as entity
+// into pages of endpoint ep, starting from the page at query.
+func newIterator(api *api, ep endpoint, entity string, query url.Values) *Iterator {
+	if query == nil {
+		query = url.Values{}
+	}
+	return &Iterator{api: api, ep: ep, entity: entity, query: query}
+}
+
+// NewStaticIterator returns Iterator over items that reports err, if
+// not nil, once the items run out. It stands in for list methods in
+// fakes and tests, where there is no amoCRM to fetch pages from.
+func NewStaticIterator(items []json.RawMessage, err error) *Iterator {
+	return &Iterator{entity: "items", items: items, tail: err}
+}
+
+// errIterator returns Iterator failed with err before the first page.
+func errIterator(err error) *Iterator {
+	return &Iterator{err: err}
+}
+
+// Next advances the iterator to the next item fetching the next page
+// when needed. It returns false when there are no more items or an
+// error occurred, which is reported by Err.
+func (it *Iterator) Next(ctx context.Context) bool {
+	for it.err == nil {
+		if len(it.items) > 0 {
+			it.cur, it.items = it.items[0], it.items[1:]
+			return true
+		}
+
+		if it.query == nil {
+			it.err = it.tail
+			break
+		}
+
+		if err := ctx.Err(); err != nil {
+			it.err = err
+			break
+		}
+
+		it.err = it.fetch(ctx)
+	}
+
+	it.cur = nil
+	return false
+}
+
+// Raw returns JSON of the current item.
+func (it *Iterator) Raw() json.RawMessage {
+	return it.cur
+}
+
+// Decode decodes the current item into v.
+func (it *Iterator) Decode(v interface{}) error {
+	if it.cur == nil {
+		return fmt.Errorf("decode %s: no current item", it.entity)
+	}
+	if err := json.Unmarshal(it.cur, v); err != nil {
+		return fmt.Errorf("decode %s: %w", it.entity, err)
+	}
+	return nil
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// Stream sends remaining items to the returned channel, which is
+// closed when the iteration stops. Check Err after the channel is
+// drained and do not use the iterator otherwise until then. Cancel
+// ctx to stop reading early and release the sending goroutine.
+func (it *Iterator) Stream(ctx context.Context) <-chan json.RawMessage {
+	ch := make(chan json.RawMessage)
+
+	go func() {
+		defer close(ch)
+
+		for it.Next(ctx) {
+			select {
+			case ch <- it.cur:
+			case <-ctx.Done():
+				it.err = ctx.Err()
+				return
+			}
+		}
+	}()
+
+	return ch
+}
+
+// maxLimit is the maximum number of items
+// amoCRM returns on a single page.
+const maxLimit = 250
+
+// pageQuery adds pagination parameters to query.
+// Zero page and limit are left to amoCRM defaults.
+func pageQuery(query url.Values, page, limit int) error {
+	if limit < 0 || limit > maxLimit {
+		return fmt.Errorf("limit must be between 1 and %d, got %d", maxLimit, limit)
+	}
+	if page < 0 {
+		return fmt.Errorf("page must be positive, got %d", page)
+	}
+
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if page > 0 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	return nil
+}
+
+func (it *Iterator) fetch(ctx context.Context) error {
+	var p Page
+	if err := it.api.request(ctx, http.MethodGet, it.ep, it.query, nil, &p); err != nil {
+		return fmt.Errorf("fetch %s: %w", it.entity, err)
+	}
+
+	// Only the query of the next page link is used, so requests
+	// keep going to the host the client is configured with.
+	next, err := p.NextQuery()
+	if err != nil {
+		return fmt.Errorf("fetch %s: %w", it.entity, err)
+	}
+	it.query = next
+
+	return p.Decode(it.entity, &it.items)
+}
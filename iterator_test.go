@@ -0,0 +1,174 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alexeykhan/amocrm"
+)
+
+// newPagedServer serves leads list with the given number of pages of two
+// leads each, followed by 204 No Content. Next page links point to the
+// real amoCRM host to make sure only their query is used.
+func newPagedServer(t *testing.T, pages int) (amocrm.Client, *[]string) {
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v4/leads", r.URL.Path)
+		queries = append(queries, r.URL.RawQuery)
+
+		page := 1
+		if p := r.URL.Query().Get("page"); p != "" {
+			_, _ = fmt.Sscan(p, &page)
+		}
+		if page > pages {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next := fmt.Sprintf(`,"next":{"href":"https://example.amocrm.ru/api/v4/leads?limit=2&page=%d"}`, page+1)
+		_, _ = fmt.Fprintf(w, `{"_page":%d,"_links":{"self":{"href":"self"}%s},"_embedded":{"leads":[{"id":%d},{"id":%d}]}}`,
+			page, next, page*10+1, page*10+2)
+	}))
+	t.Cleanup(srv.Close)
+
+	cl := amocrm.New(clientID, clientSecret, redirectURL, amocrm.WithBaseURL(srv.URL), amocrm.WithLimiter(nil))
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+	require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Time{})))
+
+	return cl, &queries
+}
+
+func TestIterator(t *testing.T) {
+	cl, queries := newPagedServer(t, 2)
+	ctx := context.Background()
+
+	var ids []int
+	it := cl.Leads().List(amocrm.LeadsConfig{Limit: 2})
+	for it.Next(ctx) {
+		var lead amocrm.Lead
+		require.NoError(t, it.Decode(&lead))
+		ids = append(ids, lead.ID)
+	}
+
+	require.NoError(t, it.Err())
+	require.Equal(t, []int{11, 12, 21, 22}, ids)
+	require.Equal(t, []string{"limit=2", "limit=2&page=2", "limit=2&page=3"}, *queries)
+
+	// Exhausted iterator stays exhausted.
+	require.False(t, it.Next(ctx))
+	require.Nil(t, it.Raw())
+	require.Error(t, it.Decode(&amocrm.Lead{}))
+}
+
+func TestIterator_Empty(t *testing.T) {
+	cl, queries := newPagedServer(t, 0)
+
+	it := cl.Leads().List(amocrm.LeadsConfig{})
+	require.False(t, it.Next(context.Background()))
+	require.NoError(t, it.Err())
+	require.Len(t, *queries, 1)
+}
+
+func TestIterator_Canceled(t *testing.T) {
+	cl, queries := newPagedServer(t, 5)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	it := cl.Leads().List(amocrm.LeadsConfig{Limit: 2})
+	require.True(t, it.Next(ctx))
+	require.True(t, it.Next(ctx))
+
+	cancel()
+	require.False(t, it.Next(ctx))
+	require.True(t, errors.Is(it.Err(), context.Canceled))
+	require.Len(t, *queries, 1)
+}
+
+func TestIterator_Stream(t *testing.T) {
+	cl, _ := newPagedServer(t, 3)
+
+	var ids []int
+	it := cl.Leads().List(amocrm.LeadsConfig{Limit: 2})
+	for raw := range it.Stream(context.Background()) {
+		var lead amocrm.Lead
+		require.NoError(t, json.Unmarshal(raw, &lead))
+		ids = append(ids, lead.ID)
+	}
+
+	require.NoError(t, it.Err())
+	require.Equal(t, []int{11, 12, 21, 22, 31, 32}, ids)
+}
+
+func TestIterator_StreamCanceled(t *testing.T) {
+	cl, _ := newPagedServer(t, 3)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	it := cl.Leads().List(amocrm.LeadsConfig{Limit: 2})
+	ch := it.Stream(ctx)
+	<-ch
+	cancel()
+
+	// Drain whatever was sent before the cancellation was noticed.
+	for range ch {
+	}
+	require.True(t, errors.Is(it.Err(), context.Canceled))
+}
+
+func TestIterator_Error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	cl := amocrm.New(clientID, clientSecret, redirectURL, amocrm.WithBaseURL(srv.URL))
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+	require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Time{})))
+
+	it := cl.Leads().List(amocrm.LeadsConfig{})
+	require.False(t, it.Next(context.Background()))
+	require.EqualError(t, it.Err(), "fetch leads: amocrm: authorization required again: amocrm: 401 Unauthorized; "+
+		"refresh token: oauth2: fetch token: amocrm: 401 Unauthorized")
+	require.True(t, amocrm.IsUnauthorized(it.Err()))
+	require.True(t, amocrm.IsReauthRequired(it.Err()))
+}
+
+func TestNewStaticIterator(t *testing.T) {
+	errBoom := errors.New("boom")
+	it := amocrm.NewStaticIterator([]json.RawMessage{json.RawMessage(`{"id":1}`), json.RawMessage(`{"id":2}`)}, errBoom)
+
+	var ids []int
+	for it.Next(context.Background()) {
+		var lead amocrm.Lead
+		require.NoError(t, it.Decode(&lead))
+		ids = append(ids, lead.ID)
+	}
+	require.Equal(t, []int{1, 2}, ids)
+	require.True(t, errors.Is(it.Err(), errBoom))
+}
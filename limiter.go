@@ -0,0 +1,155 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// amoCRM allows about 7 requests per second per integration and account.
+const (
+	defaultLimiterRPS   = 7
+	defaultLimiterBurst = 7
+)
+
+// ErrRateLimitExceeded is returned by Limiter when a request
+// can not be allowed before context deadline.
+var ErrRateLimitExceeded = errors.New("amocrm: rate limit exceeded")
+
+// Limiter throttles requests sent to amoCRM accounts. Implementations
+// must be safe for concurrent use, so one Limiter can be shared by
+// many Client instances.
+type Limiter interface {
+	// Wait blocks until a request to the account with given domain is
+	// allowed. It fails fast with ErrRateLimitExceeded when the wait
+	// would outlast ctx deadline and returns ctx error if ctx is done.
+	Wait(ctx context.Context, domain string) error
+}
+
+// Verify interface compliance.
+var _ Limiter = (*tokenBucketLimiter)(nil)
+
+// tokenBucketLimiter implements Limiter with a token bucket per domain.
+type tokenBucketLimiter struct {
+	mu      sync.Mutex
+	rps     float64
+	burst   float64
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter allocates and returns a new token bucket Limiter allowing
+// rps requests per second per account with bursts of at most burst
+// requests. Non-positive rps means no limit.
+func NewLimiter(rps float64, burst int) Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &tokenBucketLimiter{
+		rps:     rps,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Wait implements Limiter interface.
+func (l *tokenBucketLimiter) Wait(ctx context.Context, domain string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if l.rps <= 0 {
+		return nil
+	}
+
+	delay, ok := l.reserve(ctx, domain, time.Now())
+	if !ok {
+		return ErrRateLimitExceeded
+	}
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.cancel(domain)
+		return ctx.Err()
+	}
+}
+
+// reserve takes a token from domain bucket and returns how long the
+// caller must wait before using it. Nothing is taken when the wait
+// would outlast ctx deadline.
+func (l *tokenBucketLimiter) reserve(ctx context.Context, domain string, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[domain]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[domain] = b
+	}
+
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * l.rps
+		if b.tokens > l.burst {
+			b.tokens = l.burst
+		}
+		b.last = now
+	}
+
+	var delay time.Duration
+	if b.tokens < 1 {
+		delay = time.Duration((1 - b.tokens) / l.rps * float64(time.Second))
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && delay > 0 && deadline.Before(now.Add(delay)) {
+		return 0, false
+	}
+
+	b.tokens--
+	return delay, true
+}
+
+// cancel returns a reserved token back to domain bucket.
+func (l *tokenBucketLimiter) cancel(domain string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if b, ok := l.buckets[domain]; ok {
+		b.tokens++
+		if b.tokens > l.burst {
+			b.tokens = l.burst
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alexeykhan/amocrm"
+)
+
+func TestLimiter_Burst(t *testing.T) {
+	limiter := amocrm.NewLimiter(20, 2)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		require.NoError(t, limiter.Wait(ctx, "example.amocrm.ru"))
+	}
+
+	// Two requests fit into the burst, two more wait 50ms each.
+	require.True(t, time.Since(start) >= 90*time.Millisecond)
+}
+
+func TestLimiter_PerDomain(t *testing.T) {
+	limiter := amocrm.NewLimiter(1, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	require.NoError(t, limiter.Wait(ctx, "first.amocrm.ru"))
+	require.NoError(t, limiter.Wait(ctx, "second.amocrm.ru"))
+}
+
+func TestLimiter_FailFast(t *testing.T) {
+	limiter := amocrm.NewLimiter(1, 1)
+	require.NoError(t, limiter.Wait(context.Background(), "example.amocrm.ru"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := limiter.Wait(ctx, "example.amocrm.ru")
+	require.True(t, errors.Is(err, amocrm.ErrRateLimitExceeded))
+	require.True(t, time.Since(start) < 50*time.Millisecond)
+}
+
+func TestLimiter_Canceled(t *testing.T) {
+	limiter := amocrm.NewLimiter(1, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.True(t, errors.Is(limiter.Wait(ctx, "example.amocrm.ru"), context.Canceled))
+}
+
+func TestLimiter_Unlimited(t *testing.T) {
+	limiter := amocrm.NewLimiter(0, 0)
+	for i := 0; i < 100; i++ {
+		require.NoError(t, limiter.Wait(context.Background(), "example.amocrm.ru"))
+	}
+}
+
+type countingLimiter struct {
+	mu      sync.Mutex
+	domains []string
+}
+
+func (l *countingLimiter) Wait(_ context.Context, domain string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.domains = append(l.domains, domain)
+	return nil
+}
+
+func TestWithLimiter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("{}"))
+	}))
+	defer srv.Close()
+
+	limiter := &countingLimiter{}
+	for _, domain := range []string{"first.amocrm.ru", "second.amocrm.ru"} {
+		cl := amocrm.New(clientID, clientSecret, redirectURL, amocrm.WithBaseURL(srv.URL), amocrm.WithLimiter(limiter))
+		require.NoError(t, cl.SetDomain(domain))
+		require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Time{})))
+
+		_, err := cl.Accounts().Current(amocrm.AccountsConfig{})
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, []string{"first.amocrm.ru", "second.amocrm.ru"}, limiter.domains)
+}
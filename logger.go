@@ -0,0 +1,139 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Kinds of events passed to Logger.
+const (
+	EventRequest  = "request"
+	EventResponse = "response"
+	EventRefresh  = "refresh"
+	EventRetry    = "retry"
+)
+
+// redacted replaces secrets in logs and error messages.
+const redacted = "[REDACTED]"
+
+// minSecretLength keeps too short values, which are never real
+// secrets, from mangling unrelated parts of error messages.
+const minSecretLength = 8
+
+// Event is a structured record the client passes to Logger. Secrets,
+// i.e. authorization headers, client secret, authorization codes and
+// tokens, are always redacted.
+type Event struct {
+	// Kind is one of EventRequest, EventResponse,
+	// EventRefresh and EventRetry.
+	Kind     string
+	Domain   string
+	Method   string
+	Endpoint string
+	Attempt  int
+	// Header holds request headers of EventRequest.
+	Header http.Header
+	// Status holds response status code of EventResponse.
+	Status int
+	// Duration is how long the request or the refresh took.
+	Duration time.Duration
+	// Delay is how long the client waits before the next attempt.
+	Delay time.Duration
+	Err   error
+}
+
+// Logger receives structured events about requests, responses, token
+// refreshes and retries. It must be safe for concurrent use.
+type Logger interface {
+	Log(ctx context.Context, e Event)
+}
+
+// LoggerFunc is an adapter to allow the use of
+// ordinary functions as Logger.
+type LoggerFunc func(ctx context.Context, e Event)
+
+// Log implements Logger interface.
+func (f LoggerFunc) Log(ctx context.Context, e Event) {
+	f(ctx, e)
+}
+
+// log passes event to logger, if any.
+func (a *api) log(ctx context.Context, e Event) {
+	if a.logger == nil {
+		return
+	}
+	if e.Domain == "" {
+		e.Domain = a.currentDomain()
+	}
+	a.logger.Log(ctx, e)
+}
+
+// redactHeader returns a copy of header with credentials redacted.
+func redactHeader(header http.Header) http.Header {
+	h := header.Clone()
+	for _, key := range []string{"Authorization", "Cookie", "Set-Cookie"} {
+		if _, ok := h[key]; ok {
+			h[key] = []string{redacted}
+		}
+	}
+	return h
+}
+
+// redactedError hides secrets in the message of the wrapped error
+// while keeping it available for errors.Is and errors.As.
+type redactedError struct {
+	err error
+	msg string
+}
+
+// Error implements error interface.
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+// Unwrap returns the original error.
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// redactError replaces every occurrence of secrets in err message.
+// The error is returned as is if it contains none of them.
+func redactError(err error, secrets ...string) error {
+	if err == nil {
+		return nil
+	}
+
+	msg := err.Error()
+	for _, secret := range secrets {
+		if len(secret) >= minSecretLength {
+			msg = strings.ReplaceAll(msg, secret, redacted)
+		}
+	}
+
+	if msg == err.Error() {
+		return err
+	}
+	return &redactedError{err: err, msg: msg}
+}
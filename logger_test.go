@@ -0,0 +1,133 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alexeykhan/amocrm"
+)
+
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []amocrm.Event
+}
+
+func (r *eventRecorder) Log(_ context.Context, e amocrm.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func TestWithLogger(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth2/access_token" {
+			_, _ = w.Write([]byte(`{"access_token":"fresh_access","refresh_token":"next","token_type":"bearer","expires_in":86400}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}))
+	defer srv.Close()
+
+	logger := &eventRecorder{}
+	cl := amocrm.New(clientID, clientSecret, redirectURL, amocrm.WithBaseURL(srv.URL), amocrm.WithLogger(logger))
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+	require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Now())))
+
+	_, err := cl.Accounts().Current(amocrm.AccountsConfig{})
+	require.NoError(t, err)
+
+	var kinds []string
+	for _, e := range logger.events {
+		require.Equal(t, "example.amocrm.ru", e.Domain)
+		kinds = append(kinds, e.Kind+" "+e.Endpoint)
+	}
+	require.Equal(t, []string{
+		"request /oauth2/access_token",
+		"response /oauth2/access_token",
+		"refresh /oauth2/access_token",
+		"request /api/v4/account",
+		"response /api/v4/account",
+	}, kinds)
+
+	apiRequest := logger.events[3]
+	require.Equal(t, []string{"[REDACTED]"}, apiRequest.Header["Authorization"])
+	require.Equal(t, http.StatusOK, logger.events[4].Status)
+}
+
+type leakyError struct {
+	msg string
+}
+
+func (e *leakyError) Error() string {
+	return e.msg
+}
+
+func TestWithLogger_RedactsErrors(t *testing.T) {
+	logger := &eventRecorder{}
+	cl := amocrm.New(clientID, clientSecret, redirectURL,
+		amocrm.WithLogger(logger),
+		amocrm.WithMiddleware(func(next amocrm.Handler) amocrm.Handler {
+			return func(req *amocrm.Request) (*http.Response, error) {
+				return nil, &leakyError{msg: fmt.Sprintf("%s %s", clientSecret, req.HTTP.Header.Get("Authorization"))}
+			}
+		}),
+	)
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+	require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Time{})))
+
+	_, err := cl.Accounts().Current(amocrm.AccountsConfig{})
+	require.EqualError(t, err, "get accounts: [REDACTED] Bearer [REDACTED]")
+
+	var leaky *leakyError
+	require.True(t, errors.As(err, &leaky))
+
+	last := logger.events[len(logger.events)-1]
+	require.Equal(t, amocrm.EventResponse, last.Kind)
+	require.EqualError(t, last.Err, "[REDACTED] Bearer [REDACTED]")
+}
+
+func TestTokenByCode_RedactsErrors(t *testing.T) {
+	code := "def502000ba3e1724cac79"
+	cl := amocrm.New(clientID, clientSecret, redirectURL,
+		amocrm.WithMiddleware(func(next amocrm.Handler) amocrm.Handler {
+			return func(req *amocrm.Request) (*http.Response, error) {
+				require.NoError(t, req.HTTP.ParseForm())
+				return nil, errors.New(req.HTTP.PostForm.Encode())
+			}
+		}),
+	)
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+
+	_, err := cl.TokenByCode(code)
+	require.Error(t, err)
+	require.NotContains(t, err.Error(), clientSecret)
+	require.NotContains(t, err.Error(), code)
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Metrics receives measurements of requests made by the client.
+// Implementations must be safe for concurrent use.
+type Metrics interface {
+	// ObserveRequest is called after every attempt to send a request.
+	// Status is zero if no response was received. Retries is the
+	// number of attempts made before this one.
+	ObserveRequest(domain, method, endpoint string, status int, duration time.Duration, retries int)
+	// ObserveLimiterWait is called with time spent
+	// waiting for rate limiter before a request.
+	ObserveLimiterWait(domain string, wait time.Duration)
+	// ObserveTokenRefresh is called after every token
+	// refresh with its outcome.
+	ObserveTokenRefresh(domain string, err error)
+}
+
+// Verify interface compliance.
+var (
+	_ Metrics      = (*CounterMetrics)(nil)
+	_ http.Handler = (*CounterMetrics)(nil)
+)
+
+// CounterMetrics implements Metrics counting observations in memory.
+// It serves snapshot of the counters as JSON, so it can be mounted
+// to a /metrics handler as is.
+type CounterMetrics struct {
+	mu        sync.Mutex
+	requests  map[requestKey]*RequestStats
+	waits     int64
+	waitTime  time.Duration
+	refreshes int64
+	failures  int64
+}
+
+type requestKey struct {
+	domain   string
+	method   string
+	endpoint string
+	status   int
+}
+
+// RequestStats holds counters of requests with the same
+// domain, method, endpoint and response status. Retries counts
+// attempts that repeated a failed one, Duration is the total time
+// spent by all the attempts.
+type RequestStats struct {
+	Domain   string        `json:"domain"`
+	Method   string        `json:"method"`
+	Endpoint string        `json:"endpoint"`
+	Status   int           `json:"status"`
+	Count    int64         `json:"count"`
+	Retries  int64         `json:"retries"`
+	Duration time.Duration `json:"duration"`
+}
+
+// MetricsSnapshot is a point-in-time copy of CounterMetrics.
+type MetricsSnapshot struct {
+	Requests             []RequestStats `json:"requests"`
+	LimiterWaits         int64          `json:"limiter_waits"`
+	LimiterWaitTime      time.Duration  `json:"limiter_wait_time"`
+	TokenRefreshes       int64          `json:"token_refreshes"`
+	TokenRefreshFailures int64          `json:"token_refresh_failures"`
+}
+
+// NewCounterMetrics allocates and returns a new CounterMetrics.
+func NewCounterMetrics() *CounterMetrics {
+	return &CounterMetrics{requests: make(map[requestKey]*RequestStats)}
+}
+
+// ObserveRequest implements Metrics interface.
+func (m *CounterMetrics) ObserveRequest(domain, method, endpoint string, status int, duration time.Duration, retries int) {
+	key := requestKey{domain: domain, method: method, endpoint: endpoint, status: status}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats, ok := m.requests[key]
+	if !ok {
+		stats = &RequestStats{Domain: domain, Method: method, Endpoint: endpoint, Status: status}
+		m.requests[key] = stats
+	}
+	stats.Count++
+	stats.Duration += duration
+	if retries > 0 {
+		stats.Retries++
+	}
+}
+
+// ObserveLimiterWait implements Metrics interface.
+func (m *CounterMetrics) ObserveLimiterWait(_ string, wait time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.waits++
+	m.waitTime += wait
+}
+
+// ObserveTokenRefresh implements Metrics interface.
+func (m *CounterMetrics) ObserveTokenRefresh(_ string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.refreshes++
+	if err != nil {
+		m.failures++
+	}
+}
+
+// Snapshot returns current values of the counters.
+// Request stats are sorted by domain, endpoint, method and status.
+func (m *CounterMetrics) Snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := MetricsSnapshot{
+		Requests:             make([]RequestStats, 0, len(m.requests)),
+		LimiterWaits:         m.waits,
+		LimiterWaitTime:      m.waitTime,
+		TokenRefreshes:       m.refreshes,
+		TokenRefreshFailures: m.failures,
+	}
+	for _, stats := range m.requests {
+		snapshot.Requests = append(snapshot.Requests, *stats)
+	}
+
+	sort.Slice(snapshot.Requests, func(i, j int) bool {
+		a, b := snapshot.Requests[i], snapshot.Requests[j]
+		if a.Domain != b.Domain {
+			return a.Domain < b.Domain
+		}
+		if a.Endpoint != b.Endpoint {
+			return a.Endpoint < b.Endpoint
+		}
+		if a.Method != b.Method {
+			return a.Method < b.Method
+		}
+		return a.Status < b.Status
+	})
+
+	return snapshot
+}
+
+// ServeHTTP writes snapshot of the counters as JSON.
+func (m *CounterMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(m.Snapshot())
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alexeykhan/amocrm"
+)
+
+func TestWithMetrics(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth2/access_token" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}))
+	defer srv.Close()
+
+	metrics := amocrm.NewCounterMetrics()
+	cl := amocrm.New(clientID, clientSecret, redirectURL,
+		amocrm.WithBaseURL(srv.URL),
+		amocrm.WithMetrics(metrics),
+		amocrm.WithRetryPolicy(amocrm.RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond}),
+	)
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+	require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Time{})))
+
+	_, err := cl.Accounts().Current(amocrm.AccountsConfig{})
+	require.NoError(t, err)
+
+	require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Now())))
+	_, err = cl.Accounts().Current(amocrm.AccountsConfig{})
+	require.Error(t, err)
+
+	snapshot := metrics.Snapshot()
+	require.Len(t, snapshot.Requests, 3)
+
+	okStats, limitedStats, tokenStats := snapshot.Requests[0], snapshot.Requests[1], snapshot.Requests[2]
+	require.Equal(t, "/api/v4/account", okStats.Endpoint)
+	require.Equal(t, http.StatusOK, okStats.Status)
+	require.Equal(t, int64(1), okStats.Count)
+	require.Equal(t, int64(1), okStats.Retries)
+	require.Equal(t, http.StatusTooManyRequests, limitedStats.Status)
+	require.Equal(t, int64(0), limitedStats.Retries)
+	require.Equal(t, "/oauth2/access_token", tokenStats.Endpoint)
+	require.Equal(t, http.MethodPost, tokenStats.Method)
+	require.Equal(t, "example.amocrm.ru", tokenStats.Domain)
+
+	require.Equal(t, int64(3), snapshot.LimiterWaits)
+	require.Equal(t, int64(1), snapshot.TokenRefreshes)
+	require.Equal(t, int64(1), snapshot.TokenRefreshFailures)
+}
+
+func TestCounterMetrics_ServeHTTP(t *testing.T) {
+	metrics := amocrm.NewCounterMetrics()
+	metrics.ObserveRequest("example.amocrm.ru", http.MethodGet, "/api/v4/account", http.StatusOK, time.Second, 0)
+	metrics.ObserveLimiterWait("example.amocrm.ru", time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	metrics.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var got amocrm.MetricsSnapshot
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Equal(t, metrics.Snapshot(), got)
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm
+
+import (
+	"net/http"
+)
+
+// Request describes a single attempt to send a request to amoCRM
+// as seen by middlewares.
+type Request struct {
+	// Endpoint is the path of requested endpoint, e.g.
+	// "/api/v4/account" or "/oauth2/access_token".
+	Endpoint string
+	// Method is the HTTP method of the request.
+	Method string
+	// Attempt is the number of the attempt starting from 1.
+	Attempt int
+	// HTTP is the request to be sent. Middlewares may modify its
+	// headers or replace it with a derived one, e.g. with WithContext.
+	HTTP *http.Request
+}
+
+// Handler sends a request to amoCRM. Non-2xx responses are returned
+// as *APIError, so middlewares see the decoded error.
+type Handler func(req *Request) (*http.Response, error)
+
+// Middleware wraps Handler to add cross-cutting behaviour
+// to every request made by the client.
+type Middleware func(next Handler) Handler
+
+// chain wraps handler with middlewares so that the
+// first middleware is the outermost one.
+func chain(handler Handler, middlewares []Middleware) Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// roundTrip is the innermost Handler actually sending the request.
+// In dry-run mode writes are captured in the plan instead.
+func (a *api) roundTrip(req *Request) (*http.Response, error) {
+	if a.plan != nil && isWrite(req) {
+		return a.dryRun(req)
+	}
+
+	resp, err := a.http.Do(req.HTTP)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = checkResponse(resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
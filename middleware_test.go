@@ -0,0 +1,102 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alexeykhan/amocrm"
+)
+
+func TestWithMiddleware(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "trace-id", r.Header.Get("X-Request-Id"))
+		if r.URL.Path == "/oauth2/access_token" {
+			_, _ = w.Write([]byte(`{"access_token":"fresh","refresh_token":"next","token_type":"bearer","expires_in":86400}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	var trace []string
+	tracing := func(name string) amocrm.Middleware {
+		return func(next amocrm.Handler) amocrm.Handler {
+			return func(req *amocrm.Request) (*http.Response, error) {
+				req.HTTP.Header.Set("X-Request-Id", "trace-id")
+				resp, err := next(req)
+				status := "ok"
+				if amocrm.IsNotFound(err) {
+					status = "not found"
+				}
+				trace = append(trace, name+" "+req.Method+" "+req.Endpoint+" "+status)
+				return resp, err
+			}
+		}
+	}
+
+	cl := amocrm.New(clientID, clientSecret, redirectURL,
+		amocrm.WithBaseURL(srv.URL),
+		amocrm.WithMiddleware(tracing("outer"), tracing("inner")),
+	)
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+	require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Now())))
+
+	_, err := cl.Accounts().Current(amocrm.AccountsConfig{})
+	require.True(t, amocrm.IsNotFound(err))
+	require.Equal(t, []string{
+		"inner POST /oauth2/access_token ok",
+		"outer POST /oauth2/access_token ok",
+		"inner GET /api/v4/account not found",
+		"outer GET /api/v4/account not found",
+	}, trace)
+}
+
+func TestWithMiddleware_FaultInjection(t *testing.T) {
+	errInjected := errors.New("injected")
+	var attempts []int
+
+	cl := amocrm.New(clientID, clientSecret, redirectURL,
+		amocrm.WithRetryPolicy(amocrm.RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond}),
+		amocrm.WithMiddleware(func(next amocrm.Handler) amocrm.Handler {
+			return func(req *amocrm.Request) (*http.Response, error) {
+				attempts = append(attempts, req.Attempt)
+				return nil, &amocrm.APIError{
+					StatusCode: http.StatusServiceUnavailable,
+					Title:      "Service Unavailable",
+					Detail:     errInjected.Error(),
+				}
+			}
+		}),
+	)
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+	require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Time{})))
+
+	_, err := cl.Accounts().Current(amocrm.AccountsConfig{})
+	require.EqualError(t, err, "get accounts: amocrm: giving up after 2 attempts: amocrm: 503 Service Unavailable: injected")
+	require.Equal(t, []int{1, 2}, attempts)
+}
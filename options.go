@@ -0,0 +1,168 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm
+
+import (
+	"net/http"
+	"time"
+)
+
+// Option configures amoCRM API Client.
+type Option func(*api)
+
+// WithHTTPClient sets HTTP client used to send requests.
+func WithHTTPClient(client *http.Client) Option {
+	return func(a *api) {
+		if client != nil {
+			a.http = client
+		}
+	}
+}
+
+// WithTransport sets round tripper used by HTTP client to send requests.
+// The HTTP client itself is copied, so a client passed with
+// WithHTTPClient is never modified.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(a *api) {
+		client := *a.http
+		client.Transport = rt
+		a.http = &client
+	}
+}
+
+// WithTimeout sets time limit for requests made by the client.
+// Zero means no timeout. The HTTP client is copied as in WithTransport.
+func WithTimeout(timeout time.Duration) Option {
+	return func(a *api) {
+		client := *a.http
+		client.Timeout = timeout
+		a.http = &client
+	}
+}
+
+// WithUserAgent sets User-Agent header value sent with every request.
+func WithUserAgent(ua string) Option {
+	return func(a *api) {
+		if ua != "" {
+			a.userAgent = ua
+		}
+	}
+}
+
+// WithBaseURL sends API and token requests to the given scheme and host,
+// e.g. "http://127.0.0.1:8080", instead of "https://<domain>". Accounts
+// domain is still required and validated. Useful for stand-in servers.
+func WithBaseURL(baseURL string) Option {
+	return func(a *api) {
+		a.baseURL = baseURL
+	}
+}
+
+// WithAuthorizeURL overrides the URL of amoCRM OAuth2.0 authorization
+// page set by region.
+func WithAuthorizeURL(authURL string) Option {
+	return func(a *api) {
+		if authURL != "" {
+			a.authURL = authURL
+		}
+	}
+}
+
+// WithLimiter sets rate limiter shared by API and token requests. By
+// default each client allows 7 requests per second per account.
+// Pass the same Limiter to many clients to share the budget, or
+// nil to disable rate limiting.
+func WithLimiter(limiter Limiter) Option {
+	return func(a *api) {
+		a.limiter = limiter
+	}
+}
+
+// WithRetryPolicy enables retries of requests failed with transient
+// errors. By default requests are not retried. See DefaultRetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(a *api) {
+		a.retry = policy
+	}
+}
+
+// WithTokenStore sets storage the client loads token from when none
+// is set and saves every token received from amoCRM to.
+func WithTokenStore(store TokenStore) Option {
+	return func(a *api) {
+		a.store = store
+	}
+}
+
+// WithOnTokenRefresh sets a callback invoked with every token received
+// from amoCRM, e.g. to persist it to a custom storage.
+func WithOnTokenRefresh(fn func(domain string, token Token)) Option {
+	return func(a *api) {
+		a.onRefresh = fn
+	}
+}
+
+// WithMiddleware appends middlewares wrapping every request made by
+// the client, including token exchange. The first middleware is the
+// outermost one.
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(a *api) {
+		a.middlewares = append(a.middlewares, middlewares...)
+	}
+}
+
+// WithLogger sets logger receiving structured events about requests,
+// responses, token refreshes and retries. Secrets are always redacted.
+func WithLogger(logger Logger) Option {
+	return func(a *api) {
+		a.logger = logger
+	}
+}
+
+// WithMetrics sets metrics receiving request latencies, status codes,
+// retries, rate limiter waits and token refresh outcomes.
+func WithMetrics(metrics Metrics) Option {
+	return func(a *api) {
+		a.metrics = metrics
+	}
+}
+
+// WithDryRun turns on dry-run mode: read requests are sent as usual,
+// while writes are captured in plan and get synthetic responses. Sent
+// entities are echoed back, created ones with negative synthetic IDs.
+// Print the plan or export it as JSON to review what would change.
+func WithDryRun(plan *Plan) Option {
+	return func(a *api) {
+		a.plan = plan
+	}
+}
+
+// WithRegion sets regions accounts are hosted in. Accounts domains are
+// validated against all of them, while authorization page URL is taken
+// from the first one. By default RegionRU, RegionCOM and RegionKommo
+// are accepted, and users authorize on amocrm.ru.
+func WithRegion(regions ...Region) Option {
+	return func(a *api) {
+		if len(regions) > 0 {
+			a.regions = regions
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alexeykhan/amocrm"
+)
+
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestWithBaseURL(t *testing.T) {
+	var gotUserAgent, gotPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUserAgent = r.UserAgent()
+		gotPath = r.URL.Path
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}))
+	defer srv.Close()
+
+	cl := amocrm.New(clientID, clientSecret, redirectURL,
+		amocrm.WithBaseURL(srv.URL+"/"),
+		amocrm.WithUserAgent("my-integration/1.0"),
+	)
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+	require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Time{})))
+
+	account, err := cl.Accounts().Current(amocrm.AccountsConfig{})
+	require.NoError(t, err)
+	require.Equal(t, 1, account.ID)
+	require.Equal(t, "my-integration/1.0", gotUserAgent)
+	require.Equal(t, "/api/v4/account", gotPath)
+}
+
+func TestWithTransport(t *testing.T) {
+	var called bool
+	httpClient := &http.Client{}
+	rt := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		called = true
+		require.Equal(t, "https", r.URL.Scheme)
+		require.Equal(t, "example.amocrm.ru", r.URL.Host)
+		return nil, http.ErrHandlerTimeout
+	})
+
+	cl := amocrm.New(clientID, clientSecret, redirectURL,
+		amocrm.WithHTTPClient(httpClient),
+		amocrm.WithTransport(rt),
+		amocrm.WithTimeout(time.Second),
+	)
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+	require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Time{})))
+
+	_, err := cl.Accounts().Current(amocrm.AccountsConfig{})
+	require.Error(t, err)
+	require.True(t, called)
+	require.Nil(t, httpClient.Transport)
+	require.Zero(t, httpClient.Timeout)
+}
+
+func TestWithAuthorizeURL(t *testing.T) {
+	cl := amocrm.New(clientID, clientSecret, redirectURL, amocrm.WithAuthorizeURL("http://localhost/oauth"))
+
+	authURL, err := cl.AuthorizeURL("state", amocrm.PopupMode)
+	require.NoError(t, err)
+	require.Equal(t, "http", authURL.Scheme)
+	require.Equal(t, "localhost", authURL.Host)
+	require.Equal(t, "/oauth", authURL.Path)
+	require.Equal(t, clientID, authURL.Query().Get("client_id"))
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Pool gives out clients scoped to accounts an integration is installed
+// in. Clients share HTTP client, rate limiter, token store, middlewares,
+// logger and metrics, while each of them keeps its own token. Pool and
+// its clients are safe for concurrent use.
+type Pool struct {
+	template *api
+
+	mu       sync.Mutex
+	clients  map[string]Client
+	accounts map[int]string
+}
+
+// NewPool allocates and returns a new Pool. Options are applied once
+// and shared by all the clients, so one rate limiter keeps track of
+// requests to every account. Use WithTokenStore to let clients load
+// tokens of their accounts.
+func NewPool(clientID, clientSecret, redirectURL string, opts ...Option) *Pool {
+	return &Pool{
+		template: newAPI(clientID, clientSecret, redirectURL, opts...),
+		clients:  make(map[string]Client),
+		accounts: make(map[int]string),
+	}
+}
+
+// Client returns client of account with given domain, e.g.
+// "example.amocrm.ru", or subdomain, e.g. "example", which is
+// completed with domain of the first region. Domain of returned
+// client can not be changed.
+func (p *Pool) Client(domain string) (Client, error) {
+	domain, err := p.domain(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if cl, ok := p.clients[domain]; ok {
+		return cl, nil
+	}
+
+	a := p.template.clone()
+	a.domain = domain
+	a.fixedDomain = true
+
+	cl := &amoCRM{api: a}
+	p.clients[domain] = cl
+
+	return cl, nil
+}
+
+// Register associates account ID with account domain or
+// subdomain, so the client can be found with ClientByID.
+func (p *Pool) Register(accountID int, domain string) error {
+	domain, err := p.domain(domain)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.accounts[accountID] = domain
+	p.mu.Unlock()
+
+	return nil
+}
+
+// ClientByID returns client of account registered with given ID.
+func (p *Pool) ClientByID(accountID int) (Client, error) {
+	p.mu.Lock()
+	domain, ok := p.accounts[accountID]
+	p.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown account: %d", accountID)
+	}
+
+	return p.Client(domain)
+}
+
+// domain completes subdomain with domain of the first
+// region and validates the result.
+func (p *Pool) domain(domain string) (string, error) {
+	if domain != "" && !strings.Contains(domain, ".") {
+		domain += "." + p.template.regions[0].Domain
+	}
+	if _, ok := p.template.region(domain); !ok {
+		return "", errors.New("invalid domain")
+	}
+	return domain, nil
+}
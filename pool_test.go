@@ -0,0 +1,130 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alexeykhan/amocrm"
+)
+
+func TestPool_Client(t *testing.T) {
+	pool := amocrm.NewPool(clientID, clientSecret, redirectURL)
+
+	first, err := pool.Client("first.amocrm.ru")
+	require.NoError(t, err)
+
+	same, err := pool.Client("first")
+	require.NoError(t, err)
+	require.True(t, first == same)
+
+	second, err := pool.Client("second.amocrm.com")
+	require.NoError(t, err)
+	require.False(t, first == second)
+
+	_, err = pool.Client("first.example.org")
+	require.EqualError(t, err, "invalid domain")
+
+	require.NoError(t, first.SetDomain("first.amocrm.ru"))
+	require.EqualError(t, first.SetDomain("second.amocrm.ru"), "domain is fixed by pool")
+}
+
+func TestPool_ClientByID(t *testing.T) {
+	pool := amocrm.NewPool(clientID, clientSecret, redirectURL, amocrm.WithRegion(amocrm.RegionKommo))
+
+	_, err := pool.ClientByID(1)
+	require.EqualError(t, err, "unknown account: 1")
+
+	require.EqualError(t, pool.Register(1, "example.amocrm.ru"), "invalid domain")
+	require.NoError(t, pool.Register(1, "example"))
+
+	byID, err := pool.ClientByID(1)
+	require.NoError(t, err)
+
+	byDomain, err := pool.Client("example.kommo.com")
+	require.NoError(t, err)
+	require.True(t, byID == byDomain)
+}
+
+func TestPool_Shared(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Tokens are named after account subdomains.
+		sub := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		_, _ = w.Write([]byte(`{"subdomain":"` + sub + `"}`))
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	store := amocrm.NewMemoryTokenStore()
+	limiter := &countingLimiter{}
+
+	subdomains := []string{"first", "second", "third"}
+	for _, sub := range subdomains {
+		require.NoError(t, store.Save(ctx, sub+".amocrm.ru", amocrm.NewToken(sub, refreshToken, tokenType, time.Time{})))
+	}
+
+	pool := amocrm.NewPool(clientID, clientSecret, redirectURL,
+		amocrm.WithBaseURL(srv.URL),
+		amocrm.WithTokenStore(store),
+		amocrm.WithLimiter(limiter),
+	)
+
+	var wg sync.WaitGroup
+	got := make(chan string, 30)
+	for i := 0; i < 30; i++ {
+		sub := subdomains[i%len(subdomains)]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			cl, err := pool.Client(sub)
+			if err != nil {
+				got <- err.Error()
+				return
+			}
+
+			account, err := cl.Accounts().Current(amocrm.AccountsConfig{})
+			if err != nil {
+				got <- err.Error()
+				return
+			}
+			got <- sub + ":" + account.Subdomain
+		}()
+	}
+	wg.Wait()
+	close(got)
+
+	for result := range got {
+		parts := strings.Split(result, ":")
+		require.Len(t, parts, 2, result)
+		require.Equal(t, parts[0], parts[1])
+	}
+
+	require.Len(t, limiter.domains, 30)
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm
+
+import (
+	"strings"
+)
+
+// Region describes a brand or location of amoCRM
+// that accounts are hosted in.
+type Region struct {
+	// Domain is the base domain of accounts, e.g. "amocrm.ru"
+	// for "example.amocrm.ru" account.
+	Domain string
+	// AuthorizeURL is the URL of OAuth2.0 authorization page.
+	AuthorizeURL string
+	// TokenHost is the scheme and host, e.g. "https://id.example.com",
+	// token requests are sent to. Empty means accounts domain.
+	TokenHost string
+}
+
+// Built-in regions.
+var (
+	RegionRU = Region{
+		Domain:       "amocrm.ru",
+		AuthorizeURL: "https://www.amocrm.ru/oauth",
+	}
+	RegionCOM = Region{
+		Domain:       "amocrm.com",
+		AuthorizeURL: "https://www.amocrm.com/oauth",
+	}
+	RegionKommo = Region{
+		Domain:       "kommo.com",
+		AuthorizeURL: "https://www.kommo.com/oauth",
+	}
+)
+
+// defaultRegions are accepted unless set otherwise with WithRegion.
+var defaultRegions = []Region{RegionRU, RegionCOM, RegionKommo}
+
+// region returns the region accounts domain belongs to.
+func (a *api) region(domain string) (Region, bool) {
+	for _, r := range a.regions {
+		if isValidDomain(domain, r.Domain) {
+			return r, true
+		}
+	}
+	return Region{}, false
+}
+
+// isValidDomain reports whether domain is an accounts
+// domain, i.e. a single label subdomain of base.
+func isValidDomain(domain, base string) bool {
+	if domain == "" || base == "" {
+		return false
+	}
+
+	sub := strings.TrimSuffix(domain, "."+base)
+	if sub == domain ||
+		sub == "" ||
+		sub == "www" ||
+		len(sub) > 63 ||
+		strings.Contains(sub, ".") {
+		return false
+	}
+
+	return true
+}
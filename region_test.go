@@ -0,0 +1,122 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alexeykhan/amocrm"
+)
+
+func TestWithRegion(t *testing.T) {
+	cases := []struct {
+		region   amocrm.Region
+		authHost string
+		valid    []string
+		invalid  []string
+	}{
+		{
+			region:   amocrm.RegionRU,
+			authHost: "www.amocrm.ru",
+			valid:    []string{"example.amocrm.ru"},
+			invalid:  []string{"example.amocrm.com", "example.kommo.com"},
+		},
+		{
+			region:   amocrm.RegionCOM,
+			authHost: "www.amocrm.com",
+			valid:    []string{"example.amocrm.com"},
+			invalid:  []string{"example.amocrm.ru", "example.kommo.com"},
+		},
+		{
+			region:   amocrm.RegionKommo,
+			authHost: "www.kommo.com",
+			valid:    []string{"example.kommo.com"},
+			invalid:  []string{"www.kommo.com", ".kommo.com", "kommo.com", "a.b.kommo.com", "example.amocrm.ru"},
+		},
+		{
+			region:   amocrm.Region{Domain: "crm.example.org", AuthorizeURL: "https://id.example.org/oauth"},
+			authHost: "id.example.org",
+			valid:    []string{"team.crm.example.org"},
+			invalid:  []string{"crm.example.org", "team.example.org", "team.amocrm.ru"},
+		},
+	}
+
+	for _, tc := range cases {
+		cl := amocrm.New(clientID, clientSecret, redirectURL, amocrm.WithRegion(tc.region))
+
+		authURL, err := cl.AuthorizeURL("state", amocrm.PostMessageMode)
+		require.NoError(t, err)
+		require.Equal(t, tc.authHost, authURL.Host)
+
+		for _, domain := range tc.valid {
+			require.NoError(t, cl.SetDomain(domain))
+		}
+		for _, domain := range tc.invalid {
+			require.EqualError(t, cl.SetDomain(domain), "invalid domain")
+		}
+	}
+}
+
+func TestNew_DefaultRegions(t *testing.T) {
+	cl := amocrm.New(clientID, clientSecret, redirectURL)
+
+	require.NoError(t, cl.SetDomain("example.kommo.com"))
+	require.NoError(t, cl.SetDomain("example.amocrm.com"))
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+
+	authURL, err := cl.AuthorizeURL("state", amocrm.PostMessageMode)
+	require.NoError(t, err)
+	require.Equal(t, "www.amocrm.ru", authURL.Host)
+}
+
+func TestWithRegion_Many(t *testing.T) {
+	cl := amocrm.New(clientID, clientSecret, redirectURL, amocrm.WithRegion(amocrm.RegionKommo, amocrm.RegionCOM))
+
+	authURL, err := cl.AuthorizeURL("state", amocrm.PostMessageMode)
+	require.NoError(t, err)
+	require.Equal(t, "www.kommo.com", authURL.Host)
+
+	require.NoError(t, cl.SetDomain("example.kommo.com"))
+	require.NoError(t, cl.SetDomain("example.amocrm.com"))
+	require.EqualError(t, cl.SetDomain("example.amocrm.ru"), "invalid domain")
+}
+
+func TestWithRegion_TokenHost(t *testing.T) {
+	var gotPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		_, _ = w.Write([]byte(`{"access_token":"fresh","refresh_token":"next","token_type":"bearer","expires_in":86400}`))
+	}))
+	defer srv.Close()
+
+	region := amocrm.Region{Domain: "crm.example.org", AuthorizeURL: "https://id.example.org/oauth", TokenHost: srv.URL}
+	cl := amocrm.New(clientID, clientSecret, redirectURL, amocrm.WithRegion(region))
+	require.NoError(t, cl.SetDomain("team.crm.example.org"))
+
+	token, err := cl.TokenByCode("code")
+	require.NoError(t, err)
+	require.Equal(t, "fresh", token.AccessToken())
+	require.Equal(t, "/oauth2/access_token", gotPath)
+}
@@ -23,6 +23,7 @@
 package amocrm
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/url"
@@ -42,6 +43,7 @@ const (
 // Accounts describes methods available for Accounts entity.
 type Accounts interface {
 	Current(cfg AccountsConfig) (*Account, error)
+	CurrentContext(ctx context.Context, cfg AccountsConfig) (*Account, error)
 }
 
 // Verify interface compliance.
@@ -61,7 +63,13 @@ func newAccounts(api *api) Accounts {
 }
 
 // Current returns an Accounts entity for current authorized user.
-func (a accounts) Current(cfg AccountsConfig) (dto *Account, err error) {
+func (a accounts) Current(cfg AccountsConfig) (*Account, error) {
+	return a.CurrentContext(context.Background(), cfg)
+}
+
+// CurrentContext is like Current but uses ctx to control the
+// lifetime of the request, including implicit token refresh.
+func (a accounts) CurrentContext(ctx context.Context, cfg AccountsConfig) (dto *Account, err error) {
 	query := url.Values{}
 	for _, relation := range cfg.Relations {
 		switch relation {
@@ -72,7 +80,7 @@ func (a accounts) Current(cfg AccountsConfig) (dto *Account, err error) {
 		}
 	}
 
-	resp, rErr := a.api.get(accountsEndpoint, query, nil)
+	resp, rErr := a.api.get(ctx, accountsEndpoint, query, nil)
 	if rErr != nil {
 		return dto, fmt.Errorf("get accounts: %w", rErr)
 	}
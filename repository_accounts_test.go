@@ -23,7 +23,10 @@
 package amocrm_test
 
 import (
+	"context"
 	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -33,9 +36,14 @@ import (
 )
 
 func TestAccounts_Current(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("{}"))
+	}))
+	defer srv.Close()
+
 	noTokenClient := amocrm.New(clientID, clientSecret, redirectURL)
 
-	almostValidClient := amocrm.New(clientID, clientSecret, redirectURL)
+	almostValidClient := amocrm.New(clientID, clientSecret, redirectURL, amocrm.WithBaseURL(srv.URL))
 	_ = almostValidClient.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Time{}))
 	_ = almostValidClient.SetDomain("example.amocrm.ru")
 
@@ -88,3 +96,22 @@ func TestAccounts_Current(t *testing.T) {
 		}
 	}
 }
+
+func TestAccounts_CurrentContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	validClient := amocrm.New(clientID, clientSecret, redirectURL)
+	_ = validClient.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Time{}))
+	_ = validClient.SetDomain("example.amocrm.ru")
+
+	expiredClient := amocrm.New(clientID, clientSecret, redirectURL)
+	_ = expiredClient.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Now()))
+	_ = expiredClient.SetDomain("example.amocrm.ru")
+
+	for _, cl := range []amocrm.Client{validClient, expiredClient} {
+		got, err := cl.Accounts().CurrentContext(ctx, amocrm.AccountsConfig{})
+		require.Nil(t, got)
+		require.True(t, errors.Is(err, context.Canceled))
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Contact relations.
+const (
+	WithLeads     = "leads"
+	WithCustomers = "customers"
+)
+
+// Contacts describes methods available for Contacts entity.
+type Contacts interface {
+	List(cfg ContactsConfig) *Iterator
+	Create(contacts []Contact) (BatchResult, error)
+	CreateContext(ctx context.Context, contacts []Contact) (BatchResult, error)
+	Update(contacts []Contact) (BatchResult, error)
+	UpdateContext(ctx context.Context, contacts []Contact) (BatchResult, error)
+}
+
+// Verify interface compliance.
+var _ Contacts = contacts{}
+
+type contacts struct {
+	api *api
+}
+
+// Use ContactsConfig to set contacts list parameters.
+type ContactsConfig struct {
+	Relations []string
+	// Limit is the number of contacts per page, up to 250.
+	Limit int
+	// Page is the number of the page to start from.
+	Page int
+	// Filter narrows down and sorts contacts, if set.
+	Filter *Filter
+}
+
+// contactsFilterSpec lists filters and order fields supported by contacts.
+var contactsFilterSpec = filterSpec{
+	entity: "contact",
+	filters: stringSet("id", "name", "created_by", "updated_by", "responsible_user_id",
+		"created_at", "updated_at", "closest_task_at", "custom_fields_values", "query"),
+	orders: stringSet("updated_at", "id"),
+}
+
+func newContacts(api *api) Contacts {
+	return contacts{api: api}
+}
+
+// List returns Iterator over contacts, decode them into Contact.
+func (c contacts) List(cfg ContactsConfig) *Iterator {
+	query := url.Values{}
+	for _, relation := range cfg.Relations {
+		switch relation {
+		case WithCatalogElements, WithLeads, WithCustomers:
+			query.Add("with", relation)
+		default:
+			return errIterator(fmt.Errorf("unexpected contact relation: %s", relation))
+		}
+	}
+
+	if err := pageQuery(query, cfg.Page, cfg.Limit); err != nil {
+		return errIterator(err)
+	}
+
+	if err := cfg.Filter.apply(contactsFilterSpec, query); err != nil {
+		return errIterator(err)
+	}
+
+	return newIterator(c.api, contactsEndpoint, "contacts", query)
+}
+
+// Create adds contacts to amoCRM in chunks of at most 250 contacts per
+// request. The result holds IDs of created contacts or errors, one per
+// contact in the given order, and is returned even if some of them failed.
+func (c contacts) Create(contacts []Contact) (BatchResult, error) {
+	return c.CreateContext(context.Background(), contacts)
+}
+
+// CreateContext is like Create but uses ctx to control
+// the lifetime of the requests.
+func (c contacts) CreateContext(ctx context.Context, contacts []Contact) (BatchResult, error) {
+	result, err := c.write(ctx, http.MethodPost, contacts)
+	if err != nil {
+		return result, fmt.Errorf("create contacts: %w", err)
+	}
+	return result, nil
+}
+
+// Update modifies contacts, identified by ID, in chunks of at most 250
+// contacts per request. The result is the same as of Create.
+func (c contacts) Update(contacts []Contact) (BatchResult, error) {
+	return c.UpdateContext(context.Background(), contacts)
+}
+
+// UpdateContext is like Update but uses ctx to control
+// the lifetime of the requests.
+func (c contacts) UpdateContext(ctx context.Context, contacts []Contact) (BatchResult, error) {
+	result, err := c.write(ctx, http.MethodPatch, contacts)
+	if err != nil {
+		return result, fmt.Errorf("update contacts: %w", err)
+	}
+	return result, nil
+}
+
+func (c contacts) write(ctx context.Context, method string, items []Contact) (BatchResult, error) {
+	return c.api.batch(ctx, method, contactsEndpoint, "contacts", len(items), contactsBatchLimit, func(from, to int) interface{} {
+		chunk := make([]Contact, 0, to-from)
+		for i := from; i < to; i++ {
+			item := items[i]
+			item.RequestID = strconv.Itoa(i)
+			chunk = append(chunk, item)
+		}
+		return chunk
+	})
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alexeykhan/amocrm"
+)
+
+func TestContacts_List(t *testing.T) {
+	var path, query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, query = r.URL.Path, r.URL.RawQuery
+		_, _ = w.Write([]byte(`{"_embedded":{"contacts":[{"id":1,"name":"John Doe","first_name":"John","_embedded":{"leads":[{"id":3}]}}]}}`))
+	}))
+	defer srv.Close()
+
+	cl := amocrm.New(clientID, clientSecret, redirectURL, amocrm.WithBaseURL(srv.URL))
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+	require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Time{})))
+
+	it := cl.Contacts().List(amocrm.ContactsConfig{Relations: []string{amocrm.WithLeads}})
+	require.True(t, it.Next(context.Background()))
+
+	var contact amocrm.Contact
+	require.NoError(t, it.Decode(&contact))
+	require.Equal(t, "John", contact.FirstName)
+	require.Equal(t, 3, contact.Embedded.Leads[0].ID)
+	require.Equal(t, "/api/v4/contacts", path)
+	require.Equal(t, "with=leads", query)
+
+	require.False(t, it.Next(context.Background()))
+	require.NoError(t, it.Err())
+}
+
+func TestContacts_List_InvalidConfig(t *testing.T) {
+	cl := amocrm.New(clientID, clientSecret, redirectURL)
+
+	it := cl.Contacts().List(amocrm.ContactsConfig{Relations: []string{amocrm.WithLossReason}})
+	require.False(t, it.Next(context.Background()))
+	require.EqualError(t, it.Err(), "unexpected contact relation: loss_reason")
+}
+
+func TestContacts_Update(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPatch, r.Method)
+		require.Equal(t, "/api/v4/contacts", r.URL.Path)
+
+		var contacts []amocrm.Contact
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&contacts))
+		require.Len(t, contacts, 2)
+		require.Equal(t, "0", contacts[0].RequestID)
+
+		// Request IDs are not echoed, so the order is relied on.
+		_, _ = w.Write([]byte(`{"_embedded":{"contacts":[{"id":10},{"id":20}]}}`))
+	}))
+	defer srv.Close()
+
+	cl := amocrm.New(clientID, clientSecret, redirectURL, amocrm.WithBaseURL(srv.URL))
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+	require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Time{})))
+
+	contacts := []amocrm.Contact{{ID: 10, FirstName: "John"}, {ID: 20, FirstName: "Jane"}}
+	result, err := cl.Contacts().Update(contacts)
+	require.NoError(t, err)
+	require.Equal(t, amocrm.BatchResult{{ID: 10}, {ID: 20}}, result)
+	require.Empty(t, result.Failed())
+	require.Empty(t, contacts[0].RequestID)
+}
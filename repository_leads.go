@@ -0,0 +1,146 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Lead relations.
+const (
+	WithCatalogElements        = "catalog_elements"
+	WithIsPriceModifiedByRobot = "is_price_modified_by_robot"
+	WithLossReason             = "loss_reason"
+	WithContacts               = "contacts"
+	WithOnlyDeleted            = "only_deleted"
+	WithSourceID               = "source_id"
+)
+
+// Leads describes methods available for Leads entity.
+type Leads interface {
+	List(cfg LeadsConfig) *Iterator
+	Create(leads []Lead) (BatchResult, error)
+	CreateContext(ctx context.Context, leads []Lead) (BatchResult, error)
+	Update(leads []Lead) (BatchResult, error)
+	UpdateContext(ctx context.Context, leads []Lead) (BatchResult, error)
+}
+
+// Verify interface compliance.
+var _ Leads = leads{}
+
+type leads struct {
+	api *api
+}
+
+// Use LeadsConfig to set leads list parameters.
+type LeadsConfig struct {
+	Relations []string
+	// Limit is the number of leads per page, up to 250.
+	Limit int
+	// Page is the number of the page to start from.
+	Page int
+	// Filter narrows down and sorts leads, if set.
+	Filter *Filter
+}
+
+// leadsFilterSpec lists filters and order fields supported by leads.
+var leadsFilterSpec = filterSpec{
+	entity: "lead",
+	filters: stringSet("id", "name", "price", "statuses", "pipeline_id", "created_by", "updated_by", "responsible_user_id",
+		"created_at", "updated_at", "closed_at", "closest_task_at", "custom_fields_values", "query"),
+	orders: stringSet("created_at", "updated_at", "id"),
+}
+
+func newLeads(api *api) Leads {
+	return leads{api: api}
+}
+
+// List returns Iterator over leads, decode them into Lead.
+func (l leads) List(cfg LeadsConfig) *Iterator {
+	query := url.Values{}
+	for _, relation := range cfg.Relations {
+		switch relation {
+		case WithCatalogElements, WithIsPriceModifiedByRobot, WithLossReason, WithContacts, WithOnlyDeleted, WithSourceID:
+			query.Add("with", relation)
+		default:
+			return errIterator(fmt.Errorf("unexpected lead relation: %s", relation))
+		}
+	}
+
+	if err := pageQuery(query, cfg.Page, cfg.Limit); err != nil {
+		return errIterator(err)
+	}
+
+	if err := cfg.Filter.apply(leadsFilterSpec, query); err != nil {
+		return errIterator(err)
+	}
+
+	return newIterator(l.api, leadsEndpoint, "leads", query)
+}
+
+// Create adds leads to amoCRM in chunks of at most 250 leads per
+// request. The result holds IDs of created leads or errors, one per
+// lead in the given order, and is returned even if some of them failed.
+func (l leads) Create(leads []Lead) (BatchResult, error) {
+	return l.CreateContext(context.Background(), leads)
+}
+
+// CreateContext is like Create but uses ctx to control
+// the lifetime of the requests.
+func (l leads) CreateContext(ctx context.Context, leads []Lead) (BatchResult, error) {
+	result, err := l.write(ctx, http.MethodPost, leads)
+	if err != nil {
+		return result, fmt.Errorf("create leads: %w", err)
+	}
+	return result, nil
+}
+
+// Update modifies leads, identified by ID, in chunks of at most 250
+// leads per request. The result is the same as of Create.
+func (l leads) Update(leads []Lead) (BatchResult, error) {
+	return l.UpdateContext(context.Background(), leads)
+}
+
+// UpdateContext is like Update but uses ctx to control
+// the lifetime of the requests.
+func (l leads) UpdateContext(ctx context.Context, leads []Lead) (BatchResult, error) {
+	result, err := l.write(ctx, http.MethodPatch, leads)
+	if err != nil {
+		return result, fmt.Errorf("update leads: %w", err)
+	}
+	return result, nil
+}
+
+func (l leads) write(ctx context.Context, method string, items []Lead) (BatchResult, error) {
+	return l.api.batch(ctx, method, leadsEndpoint, "leads", len(items), leadsBatchLimit, func(from, to int) interface{} {
+		chunk := make([]Lead, 0, to-from)
+		for i := from; i < to; i++ {
+			item := items[i]
+			item.RequestID = strconv.Itoa(i)
+			chunk = append(chunk, item)
+		}
+		return chunk
+	})
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alexeykhan/amocrm"
+)
+
+func TestLeads_List(t *testing.T) {
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		_, _ = w.Write([]byte(`{"_embedded":{"leads":[{"id":1,"name":"Lead","price":100,"_embedded":{"contacts":[{"id":2,"is_main":true}]}}]}}`))
+	}))
+	defer srv.Close()
+
+	cl := amocrm.New(clientID, clientSecret, redirectURL, amocrm.WithBaseURL(srv.URL))
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+	require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Time{})))
+
+	it := cl.Leads().List(amocrm.LeadsConfig{
+		Relations: []string{amocrm.WithContacts, amocrm.WithLossReason},
+		Limit:     250,
+		Page:      3,
+	})
+	require.True(t, it.Next(context.Background()))
+
+	var lead amocrm.Lead
+	require.NoError(t, it.Decode(&lead))
+	require.Equal(t, "Lead", lead.Name)
+	require.Equal(t, 100, lead.Price)
+	require.Equal(t, 2, lead.Embedded.Contacts[0].ID)
+	require.True(t, lead.Embedded.Contacts[0].IsMain)
+	require.Equal(t, "limit=250&page=3&with=contacts&with=loss_reason", query)
+}
+
+func TestLeads_List_InvalidConfig(t *testing.T) {
+	cl := amocrm.New(clientID, clientSecret, redirectURL)
+
+	cases := []struct {
+		config amocrm.LeadsConfig
+		error  string
+	}{
+		{config: amocrm.LeadsConfig{Relations: []string{amocrm.WithLeads}}, error: "unexpected lead relation: leads"},
+		{config: amocrm.LeadsConfig{Limit: 251}, error: "limit must be between 1 and 250, got 251"},
+		{config: amocrm.LeadsConfig{Page: -1}, error: "page must be positive, got -1"},
+	}
+
+	for _, tc := range cases {
+		it := cl.Leads().List(tc.config)
+		require.False(t, it.Next(context.Background()))
+		require.EqualError(t, it.Err(), tc.error)
+	}
+}
+
+func TestLeads_Create(t *testing.T) {
+	var chunks []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "/api/v4/leads", r.URL.Path)
+
+		var leads []map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&leads))
+		chunks = append(chunks, len(leads))
+
+		// Second chunk has a lead amoCRM does not accept.
+		if len(chunks) == 2 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"title":"Bad Request","status":400,"detail":"Request validation failed",` +
+				`"validation-errors":[{"request_id":"260","errors":[{"code":"NotSupportedChoice","path":"status_id","detail":"Invalid status"}]}]}`))
+			return
+		}
+
+		var created []string
+		for _, lead := range leads {
+			require.NotContains(t, lead, "id")
+			require.NotContains(t, lead, "_links")
+			created = append(created, fmt.Sprintf(`{"id":1%s,"request_id":"%s"}`, lead["request_id"], lead["request_id"]))
+		}
+		_, _ = fmt.Fprintf(w, `{"_embedded":{"leads":[%s]}}`, strings.Join(created, ","))
+	}))
+	defer srv.Close()
+
+	cl := amocrm.New(clientID, clientSecret, redirectURL, amocrm.WithBaseURL(srv.URL), amocrm.WithLimiter(nil))
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+	require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Time{})))
+
+	leads := make([]amocrm.Lead, 300)
+	for i := range leads {
+		leads[i].Name = fmt.Sprintf("Lead #%d", i)
+	}
+
+	result, err := cl.Leads().Create(leads)
+	require.EqualError(t, err, "create leads: 50 of 300 leads failed: "+
+		"amocrm: 400 Bad Request: Request validation failed; request 260: status_id: Invalid status")
+	require.Equal(t, []int{250, 50}, chunks)
+	require.Len(t, result, 300)
+
+	for i := 0; i < 250; i++ {
+		id, _ := strconv.Atoi(fmt.Sprintf("1%d", i))
+		require.Equal(t, id, result[i].ID)
+		require.NoError(t, result[i].Err)
+	}
+
+	failed := result.Failed()
+	require.Len(t, failed, 50)
+	require.Equal(t, 250, failed[0])
+
+	// Validation errors are attributed to the lead they refer to.
+	var apiErr *amocrm.APIError
+	require.True(t, errors.As(result[260].Err, &apiErr))
+	require.Len(t, apiErr.ValidationErrors, 1)
+	require.Equal(t, "status_id", apiErr.ValidationErrors[0].Errors[0].Path)
+
+	require.True(t, errors.As(result[261].Err, &apiErr))
+	require.Empty(t, apiErr.ValidationErrors)
+}
+
+func TestLeads_CreateContext_Canceled(t *testing.T) {
+	cl := amocrm.New(clientID, clientSecret, redirectURL)
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+	require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Time{})))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := cl.Leads().CreateContext(ctx, make([]amocrm.Lead, 3))
+	require.True(t, errors.Is(err, context.Canceled))
+	require.Equal(t, []int{0, 1, 2}, result.Failed())
+}
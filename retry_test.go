@@ -0,0 +1,146 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alexeykhan/amocrm"
+)
+
+func newRetryTestClient(t *testing.T, handler http.HandlerFunc, policy amocrm.RetryPolicy) amocrm.Client {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	cl := amocrm.New(clientID, clientSecret, redirectURL,
+		amocrm.WithBaseURL(srv.URL),
+		amocrm.WithRetryPolicy(policy),
+		amocrm.WithLimiter(nil),
+	)
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+	require.NoError(t, cl.SetToken(amocrm.NewToken(accessToken, refreshToken, tokenType, time.Time{})))
+
+	return cl
+}
+
+func TestRetryPolicy_Recovers(t *testing.T) {
+	var calls int32
+	var retries []int
+
+	cl := newRetryTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}, amocrm.RetryPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  time.Millisecond,
+		OnRetry: func(attempt int, err error, delay time.Duration) {
+			var apiErr *amocrm.APIError
+			require.True(t, errors.As(err, &apiErr))
+			require.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+			require.Equal(t, time.Millisecond, delay)
+			retries = append(retries, attempt)
+		},
+	})
+
+	account, err := cl.Accounts().Current(amocrm.AccountsConfig{})
+	require.NoError(t, err)
+	require.Equal(t, 1, account.ID)
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	require.Equal(t, []int{1}, retries)
+}
+
+func TestRetryPolicy_GivesUp(t *testing.T) {
+	var calls int32
+	cl := newRetryTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}, amocrm.RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond, Jitter: 0.5})
+
+	_, err := cl.Accounts().Current(amocrm.AccountsConfig{})
+	require.EqualError(t, err, "get accounts: amocrm: giving up after 3 attempts: amocrm: 502 Bad Gateway")
+	require.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	var retryErr *amocrm.RetryError
+	require.True(t, errors.As(err, &retryErr))
+	require.Equal(t, 3, retryErr.Attempts)
+
+	var apiErr *amocrm.APIError
+	require.True(t, errors.As(err, &apiErr))
+	require.Equal(t, http.StatusBadGateway, apiErr.StatusCode)
+}
+
+func TestRetryPolicy_NotRetryable(t *testing.T) {
+	var calls int32
+	cl := newRetryTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}, amocrm.DefaultRetryPolicy)
+
+	_, err := cl.Accounts().Current(amocrm.AccountsConfig{})
+	require.EqualError(t, err, "get accounts: amocrm: 400 Bad Request")
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestRetryPolicy_RetryAfter(t *testing.T) {
+	var calls int32
+	cl := newRetryTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Retry-After", "5")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}, amocrm.DefaultRetryPolicy)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Retry-After exceeds context deadline, so there is no point to wait.
+	start := time.Now()
+	_, err := cl.Accounts().CurrentContext(ctx, amocrm.AccountsConfig{})
+	require.True(t, time.Since(start) < 500*time.Millisecond)
+	require.True(t, amocrm.IsRateLimited(err))
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	var apiErr *amocrm.APIError
+	require.True(t, errors.As(err, &apiErr))
+	require.Equal(t, 5*time.Second, apiErr.RetryAfter)
+}
+
+func TestRetryPolicy_Disabled(t *testing.T) {
+	var calls int32
+	cl := newRetryTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, amocrm.RetryPolicy{})
+
+	_, err := cl.Accounts().Current(amocrm.AccountsConfig{})
+	require.EqualError(t, err, "get accounts: amocrm: 503 Service Unavailable")
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
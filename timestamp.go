@@ -0,0 +1,134 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timestamp is amoCRM time in Unix seconds, as it is sent over the
+// wire. Zero Timestamp means there is no time, e.g. an open lead has
+// no ClosedAt, and is omitted when an entity is sent to amoCRM.
+type Timestamp int64
+
+// NewTimestamp returns Timestamp of t. Zero t gives zero Timestamp.
+func NewTimestamp(t time.Time) Timestamp {
+	if t.IsZero() {
+		return 0
+	}
+	return Timestamp(t.Unix())
+}
+
+// IsZero reports whether ts is not set.
+func (ts Timestamp) IsZero() bool {
+	return ts == 0
+}
+
+// Time returns ts as time.Time in the local time zone.
+// Zero Timestamp gives zero time.Time.
+func (ts Timestamp) Time() time.Time {
+	if ts == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(ts), 0)
+}
+
+// In returns ts as time.Time in loc, e.g. the location of the
+// account returned by DatetimeSettings.Location.
+func (ts Timestamp) In(loc *time.Location) time.Time {
+	if ts == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(ts), 0).In(loc)
+}
+
+// String returns ts formatted as RFC 3339 in UTC.
+func (ts Timestamp) String() string {
+	if ts == 0 {
+		return "0"
+	}
+	return ts.In(time.UTC).Format(time.RFC3339)
+}
+
+// Location returns the time zone of the account. It is loaded by
+// name from Timezone, falling back to the fixed TimezoneOffset
+// when the name is unknown to the system time zone database.
+func (s DatetimeSettings) Location() (*time.Location, error) {
+	if s.Timezone != "" {
+		loc, err := time.LoadLocation(s.Timezone)
+		if err == nil {
+			return loc, nil
+		}
+		if s.TimezoneOffset == "" {
+			return nil, fmt.Errorf("load timezone: %w", err)
+		}
+	}
+
+	if s.TimezoneOffset == "" {
+		return nil, fmt.Errorf("account timezone is unknown")
+	}
+
+	offset, err := parseOffset(s.TimezoneOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	name := s.Timezone
+	if name == "" {
+		name = "UTC" + s.TimezoneOffset
+	}
+	return time.FixedZone(name, offset), nil
+}
+
+// parseOffset parses timezone offset in "+03:00" format to seconds.
+func parseOffset(s string) (int, error) {
+	invalid := fmt.Errorf("invalid timezone offset: %s", s)
+
+	sign, rest := 1, s
+	switch {
+	case strings.HasPrefix(rest, "+"):
+		rest = rest[1:]
+	case strings.HasPrefix(rest, "-"):
+		sign, rest = -1, rest[1:]
+	}
+
+	parts := strings.Split(rest, ":")
+	if len(parts) > 2 {
+		return 0, invalid
+	}
+
+	hours, err := strconv.Atoi(parts[0])
+	if err != nil || hours < 0 || hours > 14 {
+		return 0, invalid
+	}
+
+	var minutes int
+	if len(parts) == 2 {
+		if minutes, err = strconv.Atoi(parts[1]); err != nil || minutes < 0 || minutes > 59 {
+			return 0, invalid
+		}
+	}
+
+	return sign * (hours*3600 + minutes*60), nil
+}
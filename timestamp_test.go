@@ -0,0 +1,103 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alexeykhan/amocrm"
+)
+
+func TestTimestamp(t *testing.T) {
+	var lead amocrm.Lead
+	require.NoError(t, json.Unmarshal([]byte(`{"id":1,"created_at":1600000000,"closed_at":null}`), &lead))
+	require.Equal(t, amocrm.Timestamp(1600000000), lead.CreatedAt)
+	require.True(t, lead.ClosedAt.IsZero())
+	require.True(t, lead.ClosedAt.Time().IsZero())
+	require.True(t, lead.CreatedAt.Time().Equal(time.Unix(1600000000, 0)))
+	require.Equal(t, "2020-09-13T12:26:40Z", lead.CreatedAt.String())
+
+	moscow := time.FixedZone("MSK", 3*3600)
+	require.Equal(t, 15, lead.CreatedAt.In(moscow).Hour())
+
+	lead.UpdatedAt = amocrm.NewTimestamp(time.Unix(1700000000, 0))
+	body, err := json.Marshal(amocrm.Lead{ID: 1, UpdatedAt: lead.UpdatedAt, ClosedAt: amocrm.NewTimestamp(time.Time{})})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id":1,"updated_at":1700000000}`, string(body))
+}
+
+func TestDatetimeSettings_Location(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings amocrm.DatetimeSettings
+		offset   int
+		err      bool
+	}{
+		{
+			name:     "timezone name",
+			settings: amocrm.DatetimeSettings{Timezone: "UTC", TimezoneOffset: "+03:00"},
+			offset:   0,
+		},
+		{
+			name:     "unknown timezone name",
+			settings: amocrm.DatetimeSettings{Timezone: "Mars/Olympus", TimezoneOffset: "+03:00"},
+			offset:   3 * 3600,
+		},
+		{
+			name:     "negative offset",
+			settings: amocrm.DatetimeSettings{TimezoneOffset: "-04:30"},
+			offset:   -(4*3600 + 30*60),
+		},
+		{
+			name:     "invalid offset",
+			settings: amocrm.DatetimeSettings{TimezoneOffset: "+3h"},
+			err:      true,
+		},
+		{
+			name:     "unknown timezone",
+			settings: amocrm.DatetimeSettings{Timezone: "Mars/Olympus"},
+			err:      true,
+		},
+		{
+			name: "empty",
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			loc, err := tt.settings.Location()
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			_, offset := time.Unix(1600000000, 0).In(loc).Zone()
+			require.Equal(t, tt.offset, offset)
+		})
+	}
+}
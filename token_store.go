@@ -0,0 +1,192 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrTokenNotFound is returned by TokenStore when
+// there is no token saved for the given domain.
+var ErrTokenNotFound = errors.New("amocrm: token not found")
+
+// TokenStore persists tokens of amoCRM accounts keyed by domain.
+// Implementations must be safe for concurrent use.
+type TokenStore interface {
+	Load(ctx context.Context, domain string) (Token, error)
+	Save(ctx context.Context, domain string, token Token) error
+}
+
+// Verify interface compliance.
+var (
+	_ TokenStore = (*memoryTokenStore)(nil)
+	_ TokenStore = (*fileTokenStore)(nil)
+)
+
+// memoryTokenStore implements TokenStore keeping tokens in memory.
+type memoryTokenStore struct {
+	mu     sync.RWMutex
+	tokens map[string]Token
+}
+
+// NewMemoryTokenStore allocates and returns a new in-memory TokenStore.
+func NewMemoryTokenStore() TokenStore {
+	return &memoryTokenStore{tokens: make(map[string]Token)}
+}
+
+// Load implements TokenStore interface.
+func (s *memoryTokenStore) Load(_ context.Context, domain string) (Token, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	token, ok := s.tokens[domain]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+	return token, nil
+}
+
+// Save implements TokenStore interface.
+func (s *memoryTokenStore) Save(_ context.Context, domain string, token Token) error {
+	if token == nil {
+		return errors.New("invalid token")
+	}
+
+	s.mu.Lock()
+	s.tokens[domain] = token
+	s.mu.Unlock()
+
+	return nil
+}
+
+// storedToken is the JSON form of a token kept by fileTokenStore.
+type storedToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	TokenType    string    `json:"token_type"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// fileTokenStore implements TokenStore keeping tokens in a JSON file.
+type fileTokenStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileTokenStore allocates and returns a new TokenStore keeping
+// tokens of all accounts in a single JSON file at the given path.
+// The file is created on first save and is readable by owner only.
+func NewFileTokenStore(path string) TokenStore {
+	return &fileTokenStore{path: path}
+}
+
+// Load implements TokenStore interface.
+func (s *fileTokenStore) Load(_ context.Context, domain string) (Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	t, ok := tokens[domain]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+
+	return NewToken(t.AccessToken, t.RefreshToken, t.TokenType, t.ExpiresAt), nil
+}
+
+// Save implements TokenStore interface.
+func (s *fileTokenStore) Save(_ context.Context, domain string, token Token) error {
+	if token == nil {
+		return errors.New("invalid token")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	tokens[domain] = storedToken{
+		AccessToken:  token.AccessToken(),
+		RefreshToken: token.RefreshToken(),
+		TokenType:    token.TokenType(),
+		ExpiresAt:    token.ExpiresAt(),
+	}
+
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode tokens: %w", err)
+	}
+
+	// Write to a temporary file first, so the store is never left
+	// half-written if the process dies in the middle of saving.
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temporary file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write tokens: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("close temporary file: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("replace tokens file: %w", err)
+	}
+
+	return nil
+}
+
+func (s *fileTokenStore) read() (map[string]storedToken, error) {
+	tokens := make(map[string]storedToken)
+
+	data, err := ioutil.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return tokens, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read tokens file: %w", err)
+	}
+
+	if err = json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("decode tokens file: %w", err)
+	}
+
+	return tokens, nil
+}
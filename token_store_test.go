@@ -0,0 +1,133 @@
+// Copyright (c) 2021 Alexey Khan
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package amocrm_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alexeykhan/amocrm"
+)
+
+func TestMemoryTokenStore(t *testing.T) {
+	ctx := context.Background()
+	store := amocrm.NewMemoryTokenStore()
+
+	_, err := store.Load(ctx, "example.amocrm.ru")
+	require.True(t, errors.Is(err, amocrm.ErrTokenNotFound))
+	require.EqualError(t, store.Save(ctx, "example.amocrm.ru", nil), "invalid token")
+
+	token := amocrm.NewToken(accessToken, refreshToken, tokenType, expiresAt)
+	require.NoError(t, store.Save(ctx, "example.amocrm.ru", token))
+
+	got, err := store.Load(ctx, "example.amocrm.ru")
+	require.NoError(t, err)
+	require.Equal(t, token, got)
+}
+
+func TestFileTokenStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "amocrm")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	path := filepath.Join(dir, "tokens.json")
+	expires := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	store := amocrm.NewFileTokenStore(path)
+	_, err = store.Load(ctx, "example.amocrm.ru")
+	require.True(t, errors.Is(err, amocrm.ErrTokenNotFound))
+
+	require.NoError(t, store.Save(ctx, "example.amocrm.ru", amocrm.NewToken(accessToken, refreshToken, tokenType, expires)))
+	require.NoError(t, store.Save(ctx, "other.amocrm.ru", amocrm.NewToken("other", "other", tokenType, expires)))
+
+	// Tokens survive restart.
+	got, err := amocrm.NewFileTokenStore(path).Load(ctx, "example.amocrm.ru")
+	require.NoError(t, err)
+	require.Equal(t, accessToken, got.AccessToken())
+	require.Equal(t, refreshToken, got.RefreshToken())
+	require.Equal(t, "Bearer", got.TokenType())
+	require.True(t, expires.Equal(got.ExpiresAt()))
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("not json"), 0600))
+	_, err = store.Load(ctx, "example.amocrm.ru")
+	require.Error(t, err)
+}
+
+func TestWithTokenStore(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth2/access_token" {
+			_, _ = w.Write([]byte(`{"access_token":"fresh","refresh_token":"next","token_type":"bearer","expires_in":86400}`))
+			return
+		}
+		require.Equal(t, "Bearer fresh", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	domain := "example.amocrm.ru"
+
+	store := amocrm.NewMemoryTokenStore()
+	require.NoError(t, store.Save(ctx, domain, amocrm.NewToken(accessToken, refreshToken, tokenType, time.Now())))
+
+	var refreshed []string
+	cl := amocrm.New(clientID, clientSecret, redirectURL,
+		amocrm.WithBaseURL(srv.URL),
+		amocrm.WithTokenStore(store),
+		amocrm.WithOnTokenRefresh(func(domain string, token amocrm.Token) {
+			refreshed = append(refreshed, domain+":"+token.RefreshToken())
+		}),
+	)
+	require.NoError(t, cl.SetDomain(domain))
+
+	// Expired token is loaded from the store and refreshed.
+	_, err := cl.Accounts().Current(amocrm.AccountsConfig{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"example.amocrm.ru:next"}, refreshed)
+
+	saved, err := store.Load(ctx, domain)
+	require.NoError(t, err)
+	require.Equal(t, "fresh", saved.AccessToken())
+	require.Equal(t, "next", saved.RefreshToken())
+
+	// Token received by code is saved as well.
+	_, err = cl.TokenByCode("code")
+	require.NoError(t, err)
+	require.Len(t, refreshed, 2)
+}
+
+func TestWithTokenStore_NotFound(t *testing.T) {
+	cl := amocrm.New(clientID, clientSecret, redirectURL, amocrm.WithTokenStore(amocrm.NewMemoryTokenStore()))
+	require.NoError(t, cl.SetDomain("example.amocrm.ru"))
+
+	_, err := cl.Accounts().Current(amocrm.AccountsConfig{})
+	require.EqualError(t, err, "get accounts: invalid token")
+}